@@ -24,7 +24,7 @@ func setupRoutes(app *models.App) {
 	// CORS
 	app.Router.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
 		if c.Request.Method == "OPTIONS" {
@@ -56,34 +56,107 @@ func setupRoutes(app *models.App) {
 	api := app.Router.Group("/api")
 	api.Use(middleware.AuthMiddleware(app))
 	{
+		api.PATCH("/me", handlers.UpdateCurrentUser(app))
 		api.GET("/articles", handlers.GetArticles(app))
+		api.GET("/articles/:id", handlers.GetArticle(app))
 		api.GET("/sources", handlers.GetSources(app))
+		api.GET("/sources/discover", handlers.DiscoverFeeds(app))
+		api.GET("/sources/export.opml", handlers.ExportSources(app))
+		api.GET("/sources/:id/health", handlers.GetSourceHealth(app))
 		api.POST("/alerts", handlers.CreateAlert(app))
 		api.GET("/alerts", handlers.GetUserAlerts(app))
+		api.POST("/alerts/preview", handlers.PreviewAlert(app))
+		api.PUT("/alerts/:id", handlers.UpdateAlert(app))
+		api.PATCH("/alerts/:id", handlers.PatchAlert(app))
+		api.DELETE("/alerts/:id", handlers.DeleteAlert(app))
+		api.POST("/alerts/:id/pause", handlers.PauseAlert(app))
+		api.POST("/alerts/:id/resume", handlers.ResumeAlert(app))
+		api.POST("/alerts/:id/snooze", handlers.SnoozeAlert(app))
+		api.DELETE("/alerts/:id/snooze", handlers.UnsnoozeAlert(app))
+		api.POST("/alerts/:id/webhooks", handlers.CreateAlertWebhook(app))
+		api.GET("/alerts/:id/webhooks", handlers.GetAlertWebhooks(app))
+		api.DELETE("/alerts/:id/webhooks/:webhookId", handlers.DeleteAlertWebhook(app))
+		api.POST("/alerts/:id/webhooks/:webhookId/rotate-secret", handlers.RotateAlertWebhookSecret(app))
+		api.GET("/alerts/:id/webhooks/:webhookId/deliveries", handlers.GetWebhookDeliveries(app))
+		api.POST("/alerts/:id/webhooks/:webhookId/test", handlers.TestAlertWebhook(app))
+		api.GET("/notifications", handlers.GetNotifications(app))
+		api.POST("/notifications/read", handlers.MarkNotificationsRead(app))
+		api.POST("/notifications/unread", handlers.MarkNotificationsUnread(app))
+		api.POST("/notifications/dismiss", handlers.DismissNotifications(app))
+		api.POST("/devices", handlers.RegisterDevice(app))
+		api.GET("/devices", handlers.GetDevices(app))
+		api.DELETE("/devices/:id", handlers.DeleteDevice(app))
 		api.POST("/monitor/trigger", handlers.TriggerMonitoring(app))
+		api.GET("/monitor/runs", handlers.GetMonitoringRuns(app))
+		api.GET("/monitor/runs/:id", handlers.GetMonitoringRun(app))
+	}
+
+	// Sources are shared by every user, so only admins may change them
+	sources := api.Group("/sources")
+	sources.Use(middleware.AdminMiddleware())
+	{
+		sources.POST("", handlers.CreateSource(app))
+		sources.POST("/import", handlers.ImportSources(app))
+		sources.PUT("/:id", handlers.UpdateSource(app))
+		sources.PATCH("/:id", handlers.PatchSource(app))
+		sources.DELETE("/:id", handlers.DeleteSource(app))
+		sources.POST("/:id/refresh", handlers.RefreshSource(app))
+	}
+
+	// Admin routes (authenticated users listed in ADMIN_EMAILS)
+	admin := api.Group("/admin")
+	admin.Use(middleware.AdminMiddleware())
+	{
+		admin.GET("/locks", handlers.GetMonitorLocks(app))
+		admin.GET("/notifications/dead-letters", handlers.GetDeadLetters(app))
+		admin.POST("/notifications/dead-letters/replay", handlers.ReplayAllDeadLetters(app))
+		admin.POST("/notifications/dead-letters/:id/replay", handlers.ReplayDeadLetter(app))
 	}
 }
 
 // Start the cron scheduler
 func startScheduler(app *models.App) {
-	app.Cron = cron.New()
+	// Skip a tick if the previous one is still fetching, so a source is never polled twice at once
+	app.Cron = cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)))
 
-	// Run every 10 minutes: "*/10 * * * *"
-	app.Cron.AddFunc("*/10 * * * *", func() {
-		log.Println("⏰ CRON JOB TRIGGERED - Running RSS monitoring...")
-		if err := services.MonitorAllSources(app); err != nil {
+	// Every minute, poll whichever sources are due on their own interval
+	app.Cron.AddFunc("@every 1m", func() {
+		if err := services.MonitorDueSources(app, services.TriggerScheduled); err != nil {
 			log.Printf("Error in scheduled monitoring: %v", err)
 		}
 	})
 
+	// Work off the notification outbox, including retries that have come due
+	app.Cron.AddFunc("@every 30s", func() {
+		if _, err := services.DispatchNotifications(app); err != nil {
+			log.Printf("Error dispatching notifications: %v", err)
+		}
+	})
+
+	// Send hourly, daily and weekly digests whose send time has come
+	app.Cron.AddFunc("@every 1m", func() {
+		if _, err := services.SendDueDigests(app); err != nil {
+			log.Printf("Error sending digests: %v", err)
+		}
+	})
+
+	// Compare spike alerts' recent coverage with their baselines
+	app.Cron.AddFunc("@every 5m", func() {
+		if _, err := services.CheckSpikeAlerts(app); err != nil {
+			log.Printf("Error checking spike alerts: %v", err)
+		}
+	})
+
 	app.Cron.Start()
-	log.Println("📡 Cron scheduler started - RSS monitoring every 10 minutes")
+	log.Printf("📡 Cron scheduler started - polling due sources every minute (default interval %s)", services.DefaultPollInterval())
 }
 
 func main() {
 	app := &models.App{
-		Parser: gofeed.NewParser(),
+		Parser:     gofeed.NewParser(),
+		FeedClient: services.NewFeedHTTPClient(),
 	}
+	app.Parser.Client = app.FeedClient
 
 	// Initialize Firebase
 	if err := firebase.InitFirebase(app); err != nil {
@@ -95,6 +168,9 @@ func main() {
 		log.Fatal("Failed to initialize database:", err)
 	}
 
+	// Initialize notification channels
+	services.InitNotifiers(app)
+
 	// Setup routes
 	setupRoutes(app)
 
@@ -105,7 +181,7 @@ func main() {
 	go func() {
 		time.Sleep(10 * time.Second)
 		log.Println("Running initial RSS monitoring...")
-		if err := services.MonitorAllSources(app); err != nil {
+		if err := services.MonitorDueSources(app, services.TriggerStartup); err != nil {
 			log.Printf("Error in initial monitoring: %v", err)
 		}
 	}()
@@ -0,0 +1,51 @@
+// Command migrate runs the schema changes too heavy for the API to make at
+// startup: adding the article search column, which rewrites the articles
+// table, and building the indexes spike alerts count with. Run it once after
+// deploying a version that needs it (the API logs a warning until the search
+// column exists). Concurrent runs wait for each other, and runs with nothing
+// to do return quickly.
+package main
+
+import (
+	"log"
+
+	"github.com/mrrobotisreal/rss_today_api/internal/db"
+	"github.com/mrrobotisreal/rss_today_api/internal/services"
+	"gorm.io/gorm"
+)
+
+func main() {
+	conn, err := db.Open()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// One connection throughout, so a session-level lock covers both the
+	// transaction and the concurrent index builds, which can't run inside one
+	err = conn.Connection(func(session *gorm.DB) error {
+		if err := session.Exec("SELECT pg_advisory_lock(?, 0)", services.LockNamespaceMigrate).Error; err != nil {
+			return err
+		}
+		defer session.Exec("SELECT pg_advisory_unlock(?, 0)", services.LockNamespaceMigrate)
+
+		err := session.Transaction(func(tx *gorm.DB) error {
+			// Checked under the lock, so a run that waited on another finds its work done
+			if current, err := db.ArticleSearchCurrent(tx); err != nil || current {
+				return err
+			}
+			log.Println("Adding the article search column; this rewrites the articles table")
+			return db.MigrateArticleSearch(tx)
+		})
+		if err != nil {
+			return err
+		}
+
+		log.Println("Building missing article indexes")
+		return db.MigrateArticleIndexes(session)
+	})
+	if err != nil {
+		log.Fatal("Migration failed: ", err)
+	}
+
+	log.Println("Database migrations complete")
+}
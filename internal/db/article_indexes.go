@@ -0,0 +1,46 @@
+package db
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// articleIndexes are indexes on articles that gorm tags can't express, keyed
+// by name. Spike alerts count articles per keyword and source over
+// LEAST(pub_date, created_at) and look up when coverage began by created_at.
+var articleIndexes = []struct {
+	name, definition string
+}{
+	{"idx_articles_keywords", "USING GIN (keywords)"},
+	{"idx_articles_source_spike_time", "(source_id, (LEAST(pub_date, created_at)))"},
+	{"idx_articles_spike_time", "((LEAST(pub_date, created_at)))"},
+	{"idx_articles_source_created_at", "(source_id, created_at)"},
+	{"idx_articles_created_at", "(created_at)"},
+}
+
+// MigrateArticleIndexes builds any missing articleIndexes. They're built
+// concurrently so articles can still be written meanwhile, which can't be
+// done inside a transaction; like MigrateArticleSearch, cmd/migrate runs it.
+func MigrateArticleIndexes(db *gorm.DB) error {
+	for _, index := range articleIndexes {
+		// A build that failed part way leaves an invalid index behind, which
+		// IF NOT EXISTS would then skip, so start those again
+		var invalid bool
+		err := db.Raw(`SELECT EXISTS (SELECT 1 FROM pg_index i JOIN pg_class c ON c.oid = i.indexrelid
+			WHERE c.relname = ? AND c.relnamespace = current_schema()::regnamespace AND NOT i.indisvalid)`, index.name).Scan(&invalid).Error
+		if err != nil {
+			return err
+		}
+		if invalid {
+			if err := db.Exec("DROP INDEX CONCURRENTLY IF EXISTS " + index.name).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := db.Exec("CREATE INDEX CONCURRENTLY IF NOT EXISTS " + index.name + " ON articles " + index.definition).Error; err != nil {
+			return fmt.Errorf("%s: %v", index.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// searchVectorExpr is what the search_vector column is generated from
+const searchVectorExpr = `
+	setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+	setweight(to_tsvector('english', coalesce(description, '')), 'B') ||
+	setweight(to_tsvector('english', coalesce(content, '')), 'C')`
+
+// searchVectorExpression returns the expression the search_vector column is
+// currently generated from, or "" if there is no such column
+func searchVectorExpression(db *gorm.DB) (string, error) {
+	var expr string
+	err := db.Raw(`SELECT coalesce(generation_expression, '') FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'articles' AND column_name = 'search_vector'`).Scan(&expr).Error
+	return expr, err
+}
+
+// ArticleSearchCurrent reports whether articles has an up-to-date search_vector column
+func ArticleSearchCurrent(db *gorm.DB) (bool, error) {
+	expr, err := searchVectorExpression(db)
+	return expr != "" && strings.Contains(expr, "content"), err
+}
+
+// MigrateArticleSearch adds the full-text search column to articles. It is a
+// generated column, so Postgres keeps it in step with the article text and
+// gorm never writes it; that's also why it isn't on models.Article.
+//
+// Adding the column rewrites the whole table under an exclusive lock, so this
+// isn't run at startup; cmd/migrate runs it once, holding an advisory lock.
+func MigrateArticleSearch(db *gorm.DB) error {
+	// A generated column's expression can't be altered, so one built before
+	// articles had a content column is dropped (with its index) and rebuilt
+	expr, err := searchVectorExpression(db)
+	if err != nil {
+		return err
+	}
+	if expr != "" && !strings.Contains(expr, "content") {
+		if err := db.Exec(`ALTER TABLE articles DROP COLUMN search_vector`).Error; err != nil {
+			return err
+		}
+	}
+
+	err = db.Exec(`ALTER TABLE articles ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (` + searchVectorExpr + `) STORED`).Error
+	if err != nil {
+		return err
+	}
+
+	return db.Exec(`CREATE INDEX IF NOT EXISTS idx_articles_search_vector ON articles USING GIN (search_vector)`).Error
+}
@@ -10,25 +10,51 @@ import (
 	"gorm.io/gorm"
 )
 
-func InitDatabase(app *models.App) error {
+// Open connects to the database at DATABASE_URL
+func Open() (*gorm.DB, error) {
 	dsn := os.Getenv("DATABASE_URL")
 	if dsn == "" {
-		return fmt.Errorf("DATABASE_URL environment variable is required")
+		return nil, fmt.Errorf("DATABASE_URL environment variable is required")
 	}
 
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %v", err)
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
+	}
+	return db, nil
+}
+
+func InitDatabase(app *models.App) error {
+	db, err := Open()
+	if err != nil {
+		return err
 	}
 
 	app.DB = db
 
 	// Create all tables
-	err = db.AutoMigrate(&models.User{}, &models.NewsSource{}, &models.Article{}, &models.UserAlert{}, &models.NotificationSent{})
+	err = db.AutoMigrate(&models.User{}, &models.NewsSource{}, &models.Article{}, &models.UserAlert{}, &models.NotificationSent{},
+		&models.MonitoringRun{}, &models.MonitoringRunSourceError{}, &models.DeviceToken{},
+		&models.NotificationOutbox{}, &models.AlertWebhook{}, &models.WebhookDelivery{},
+		&models.DigestItem{}, &models.AlertDigest{}, &models.SpikeEvent{})
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %v", err)
 	}
 
+	backfillSourceURLKeys(db)
+
+	// Notifications from before created_at existed were created when first sent
+	if err := db.Exec(`UPDATE notification_sents SET created_at = sent_at WHERE created_at IS NULL`).Error; err != nil {
+		return fmt.Errorf("failed to backfill notification creation times: %v", err)
+	}
+
+	// Search needs a table rewrite to set up, which is left to cmd/migrate
+	if current, err := ArticleSearchCurrent(db); err != nil {
+		return fmt.Errorf("failed to check article search: %v", err)
+	} else if !current {
+		log.Println("⚠️ Article search column is missing or outdated; ?q= searches will fail until `go run ./cmd/migrate` is run")
+	}
+
 	// Add default news sources if they don't exist
 	AddDefaultSources(app)
 
@@ -36,6 +62,25 @@ func InitDatabase(app *models.App) error {
 	return nil
 }
 
+// backfillSourceURLKeys sets rss_url_key on sources saved before it existed.
+// A live source sharing its feed with an older one is left without a key
+// rather than failing startup; it should be merged or deleted by hand.
+func backfillSourceURLKeys(db *gorm.DB) {
+	var sources []models.NewsSource
+	if err := db.Unscoped().Select("id", "rss_url").Where("rss_url_key IS NULL").Order("id").Find(&sources).Error; err != nil {
+		log.Printf("Error loading sources to backfill rss_url_key: %v", err)
+		return
+	}
+
+	for _, source := range sources {
+		err := db.Unscoped().Model(&models.NewsSource{}).Where("id = ?", source.ID).
+			UpdateColumn("rss_url_key", models.NormalizeFeedURL(source.RSSURL)).Error
+		if err != nil {
+			log.Printf("⚠️ Source %d has the same feed as another source (%s), leaving it unkeyed: %v", source.ID, source.RSSURL, err)
+		}
+	}
+}
+
 func AddDefaultSources(app *models.App) {
 	defaultSources := []models.NewsSource{
 		// {
@@ -89,8 +134,9 @@ func AddDefaultSources(app *models.App) {
 	}
 
 	for _, source := range defaultSources {
+		// Include soft-deleted rows so a source someone removed isn't re-seeded on restart
 		var existingSource models.NewsSource
-		result := app.DB.Where("rss_url = ?", source.RSSURL).First(&existingSource)
+		result := app.DB.Unscoped().Where("rss_url = ?", source.RSSURL).First(&existingSource)
 		if result.Error != nil && result.Error == gorm.ErrRecordNotFound {
 			if err := app.DB.Create(&source).Error; err != nil {
 				log.Printf("Error creating default source %s: %v", source.Name, err)
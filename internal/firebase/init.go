@@ -33,5 +33,15 @@ func InitFirebase(app *models.App) error {
 
 	app.FirebaseAuth = authClient
 	log.Println("Firebase Auth initialized successfully")
+
+	// Push is optional: without a Messaging client InitNotifiers leaves it off
+	messagingClient, err := firebaseApp.Messaging(ctx)
+	if err != nil {
+		log.Printf("Firebase Messaging unavailable, push notifications disabled: %v", err)
+		return nil
+	}
+
+	app.Messaging = messagingClient
+	log.Println("Firebase Messaging initialized successfully")
 	return nil
 }
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"github.com/mrrobotisreal/rss_today_api/internal/services"
+	"gorm.io/gorm"
+)
+
+// CreateWebhookRequest represents the request body for registering a webhook on an alert
+type CreateWebhookRequest struct {
+	URL         string `json:"url" binding:"required"`
+	Description string `json:"description"`
+}
+
+// CreateAlertWebhook registers a webhook on one of the current user's alerts.
+// The response is the only time the signing secret is shown.
+func CreateAlertWebhook(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		alert, ok := loadOwnedAlert(c, app)
+		if !ok {
+			return
+		}
+
+		var req CreateWebhookRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		webhookURL, err := url.Parse(strings.TrimSpace(req.URL))
+		if err != nil || (webhookURL.Scheme != "http" && webhookURL.Scheme != "https") || webhookURL.Host == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "url must be an absolute http or https URL"})
+			return
+		}
+		// Checked again on every delivery, in case the name is later pointed elsewhere
+		if err := services.CheckPublicURL(c.Request.Context(), webhookURL.String()); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "url must resolve to a public address: " + err.Error()})
+			return
+		}
+
+		secret, err := services.GenerateWebhookSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		webhook := models.AlertWebhook{
+			AlertID:     alert.ID,
+			UserID:      alert.UserID,
+			URL:         webhookURL.String(),
+			Description: req.Description,
+			Secret:      secret,
+			Active:      true,
+		}
+
+		if err := app.DB.Create(&webhook).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, webhook)
+	}
+}
+
+// GetAlertWebhooks lists the webhooks on one of the current user's alerts, without secrets
+func GetAlertWebhooks(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		alert, ok := loadOwnedAlert(c, app)
+		if !ok {
+			return
+		}
+
+		var webhooks []models.AlertWebhook
+		if err := app.DB.Where("alert_id = ?", alert.ID).Order("id").Find(&webhooks).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		for i := range webhooks {
+			webhooks[i].Secret = ""
+		}
+
+		c.JSON(http.StatusOK, webhooks)
+	}
+}
+
+// DeleteAlertWebhook removes a webhook; its delivery log is kept
+func DeleteAlertWebhook(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, webhook, ok := loadOwnedWebhook(c, app)
+		if !ok {
+			return
+		}
+
+		if err := app.DB.Delete(&webhook).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// RotateAlertWebhookSecret replaces a webhook's signing secret and returns the new one
+func RotateAlertWebhookSecret(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, webhook, ok := loadOwnedWebhook(c, app)
+		if !ok {
+			return
+		}
+
+		secret, err := services.GenerateWebhookSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := app.DB.Model(&webhook).Update("secret", secret).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		webhook.Secret = secret
+
+		c.JSON(http.StatusOK, webhook)
+	}
+}
+
+// GetWebhookDeliveries returns the delivery log of a webhook, newest first
+func GetWebhookDeliveries(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, webhook, ok := loadOwnedWebhook(c, app)
+		if !ok {
+			return
+		}
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		if limit > 200 {
+			limit = 200
+		}
+
+		var deliveries []models.WebhookDelivery
+		if err := app.DB.Where("webhook_id = ?", webhook.ID).Order("created_at DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, deliveries)
+	}
+}
+
+// TestAlertWebhook sends a signed webhook.test event right away and returns the delivery
+func TestAlertWebhook(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		alert, webhook, ok := loadOwnedWebhook(c, app)
+		if !ok {
+			return
+		}
+
+		delivery, err := services.SendTestWebhook(app, webhook, alert)
+
+		status := http.StatusOK
+		if err != nil {
+			status = http.StatusBadGateway
+		}
+		c.JSON(status, delivery)
+	}
+}
+
+// loadOwnedWebhook fetches the :webhookId webhook on the current user's :id alert
+func loadOwnedWebhook(c *gin.Context, app *models.App) (models.UserAlert, models.AlertWebhook, bool) {
+	var webhook models.AlertWebhook
+
+	alert, ok := loadOwnedAlert(c, app)
+	if !ok {
+		return alert, webhook, false
+	}
+
+	webhookID, err := strconv.ParseUint(c.Param("webhookId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return alert, webhook, false
+	}
+
+	if err := app.DB.Where("id = ? AND alert_id = ?", webhookID, alert.ID).First(&webhook).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return alert, webhook, false
+	}
+
+	return alert, webhook, true
+}
@@ -2,11 +2,24 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"github.com/mrrobotisreal/rss_today_api/internal/query"
+	"github.com/mrrobotisreal/rss_today_api/internal/services"
 )
 
+// validNotificationMethods are the channels an alert may ask for
+var validNotificationMethods = map[string]bool{
+	"email":   true,
+	"push":    true,
+	"webhook": true,
+}
+
+// CreateAlert saves a new alert for the current user. With ?backfill_days=N it
+// is also matched against articles stored in the last N days, delivered as one
+// digest or, with ?backfill_delivery=each, one notification per match.
 func CreateAlert(app *models.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		user, _ := c.Get("user")
@@ -18,6 +31,27 @@ func CreateAlert(app *models.App) gin.HandlerFunc {
 			return
 		}
 
+		if !validateAlert(c, &alert) {
+			return
+		}
+
+		// Optionally match the new alert against articles from the last few days
+		backfillDays, err := strconv.Atoi(c.DefaultQuery("backfill_days", "0"))
+		if err != nil || backfillDays < 0 || backfillDays > services.BackfillMaxDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "backfill_days must be between 0 and " + strconv.Itoa(services.BackfillMaxDays)})
+			return
+		}
+		backfillDelivery := c.DefaultQuery("backfill_delivery", services.BackfillDigest)
+		if backfillDelivery != services.BackfillDigest && backfillDelivery != services.BackfillEach {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "backfill_delivery must be digest or each"})
+			return
+		}
+
+		if backfillDays > 0 && alert.Type == services.AlertTypeSpike {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Spike alerts can't be backfilled"})
+			return
+		}
+
 		alert.UserID = currentUser.ID
 
 		if err := app.DB.Create(&alert).Error; err != nil {
@@ -25,6 +59,63 @@ func CreateAlert(app *models.App) gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(http.StatusCreated, alert)
+		if backfillDays == 0 {
+			c.JSON(http.StatusCreated, alert)
+			return
+		}
+
+		response := struct {
+			models.UserAlert
+			Backfill      *services.BackfillResult `json:"backfill,omitempty"`
+			BackfillError string                   `json:"backfill_error,omitempty"`
+		}{UserAlert: alert}
+
+		// The alert is saved either way; a failed backfill is reported alongside it
+		backfill, err := services.BackfillAlert(app, alert, backfillDays, backfillDelivery)
+		if err != nil {
+			response.BackfillError = err.Error()
+		} else {
+			response.Backfill = &backfill
+		}
+
+		c.JSON(http.StatusCreated, response)
+	}
+}
+
+// validateAlert checks an alert's notification methods, type, query and
+// delivery schedule, filling in defaults. It writes a 400 and returns false
+// if anything is invalid.
+func validateAlert(c *gin.Context, alert *models.UserAlert) bool {
+	for _, method := range alert.NotificationMethods {
+		if !validNotificationMethods[method] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown notification method: " + method})
+			return false
+		}
+	}
+
+	if err := services.ValidateAlertType(alert); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	if _, err := services.AlertQuery(*alert); err != nil {
+		queryError(c, err)
+		return false
+	}
+
+	if err := services.ValidateDeliverySchedule(alert); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	return true
+}
+
+// queryError reports an invalid alert query, including where the problem is
+func queryError(c *gin.Context, err error) {
+	response := gin.H{"error": "Invalid query: " + err.Error()}
+	if syntaxErr, ok := err.(*query.SyntaxError); ok {
+		response["position"] = syntaxErr.Pos
 	}
+	c.JSON(http.StatusBadRequest, response)
 }
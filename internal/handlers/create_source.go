@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"github.com/mrrobotisreal/rss_today_api/internal/services"
+)
+
+// SourceRequest represents the request body for creating or replacing a news source.
+// When creating, RSSURL may be left empty and it will be discovered from URL.
+type SourceRequest struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	RSSURL   string `json:"rss_url"`
+	Category string `json:"category"`
+	Active   *bool  `json:"active"`
+
+	PollIntervalMinutes int  `json:"poll_interval_minutes"` // 0 = default interval
+	AdaptivePolling     bool `json:"adaptive_polling"`
+	ExtractContent      bool `json:"extract_content"` // Fetch each article's page for its full text
+}
+
+func CreateSource(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req SourceRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validatePollInterval(req.PollIntervalMinutes); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		rssURL := strings.TrimSpace(req.RSSURL)
+		if rssURL == "" {
+			if strings.TrimSpace(req.URL) == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "rss_url or url is required"})
+				return
+			}
+
+			// Editors usually only know the homepage, so look for its feed
+			candidates, err := services.DiscoverFeeds(app, req.URL)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if len(candidates) == 0 {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "No feed could be discovered at " + req.URL})
+				return
+			}
+			rssURL = candidates[0].URL
+		}
+
+		if taken, err := rssURLTaken(app, rssURL, 0); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		} else if taken {
+			c.JSON(http.StatusConflict, gin.H{"error": "A source with this RSS URL already exists"})
+			return
+		}
+
+		// Make sure the feed is actually reachable and parseable before saving it
+		feed, err := services.ValidateFeedURL(app, rssURL)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+
+		source := models.NewsSource{
+			Name:     strings.TrimSpace(req.Name),
+			URL:      strings.TrimSpace(req.URL),
+			RSSURL:   rssURL,
+			Category: strings.Trim(strings.TrimSpace(req.Category), "/"),
+			Active:   true,
+
+			PollIntervalMinutes: req.PollIntervalMinutes,
+			AdaptivePolling:     req.AdaptivePolling,
+			ExtractContent:      req.ExtractContent,
+		}
+		if req.Active != nil {
+			source.Active = *req.Active
+		}
+
+		// Fall back to what the feed says about itself
+		if source.Name == "" {
+			source.Name = feed.Title
+		}
+		if source.URL == "" {
+			source.URL = feed.Link
+		}
+		if source.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name is required when the feed has no title"})
+			return
+		}
+
+		active := source.Active
+		if err := app.DB.Create(&source).Error; err != nil {
+			if isUniqueViolation(err) {
+				c.JSON(http.StatusConflict, gin.H{"error": "A source with this RSS URL already exists"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// GORM swaps a false bool for the column default on insert, so apply it afterwards
+		if !active {
+			if err := app.DB.Model(&source).Update("active", false).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		c.JSON(http.StatusCreated, source)
+	}
+}
+
+// rssURLTaken reports whether a live source other than excludeID already uses
+// rssURL, however it's spelled. The unique index on rss_url_key has the final
+// say; this is for a friendlier answer up front.
+func rssURLTaken(app *models.App, rssURL string, excludeID uint) (bool, error) {
+	var count int64
+	query := app.DB.Model(&models.NewsSource{}).Where("rss_url_key = ?", models.NormalizeFeedURL(rssURL))
+	if excludeID != 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// isUniqueViolation reports whether err is Postgres refusing a duplicate; for
+// sources, another live source with the same feed saved in the meantime
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// validatePollInterval checks a requested poll interval in minutes; 0 means "use the default"
+func validatePollInterval(minutes int) error {
+	if minutes == 0 {
+		return nil
+	}
+	interval := time.Duration(minutes) * time.Minute
+	if interval < services.MinPollInterval || interval > services.MaxPollInterval {
+		return fmt.Errorf("poll_interval_minutes must be between %d and %d",
+			int(services.MinPollInterval/time.Minute), int(services.MaxPollInterval/time.Minute))
+	}
+	return nil
+}
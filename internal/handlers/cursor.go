@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// errInvalidCursor is returned for cursors this API didn't issue
+var errInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor packs the sort values of the last item on a page into an
+// opaque token for the next_cursor field
+func encodeCursor(values ...string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(values, "|")))
+}
+
+// decodeCursor unpacks a token from encodeCursor, checking it has n values
+func decodeCursor(cursor string, n int) ([]string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	values := strings.Split(string(raw), "|")
+	if len(values) != n {
+		return nil, errInvalidCursor
+	}
+	return values, nil
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"github.com/mrrobotisreal/rss_today_api/internal/services"
+	"gorm.io/gorm"
+)
+
+// GetDeadLetters lists notifications that exhausted their delivery attempts, newest first
+func GetDeadLetters(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		if limit > 200 {
+			limit = 200
+		}
+
+		query := app.DB.Preload("Notification").Where("status = ?", services.OutboxDead)
+		if method := c.Query("method"); method != "" {
+			query = query.Joins("JOIN notification_sents ON notification_sents.id = notification_outboxes.notification_id").
+				Where("notification_sents.method = ?", method)
+		}
+
+		var entries []models.NotificationOutbox
+		if err := query.Order("notification_outboxes.updated_at DESC").Limit(limit).Find(&entries).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		var total int64
+		app.DB.Model(&models.NotificationOutbox{}).Where("status = ?", services.OutboxDead).Count(&total)
+
+		c.JSON(http.StatusOK, gin.H{
+			"total":        total,
+			"dead_letters": entries,
+		})
+	}
+}
+
+// ReplayDeadLetter re-queues one dead-lettered notification
+func ReplayDeadLetter(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseIDParam(c)
+		if !ok {
+			return
+		}
+
+		if err := services.ReplayDeadLetter(app, id); err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Dead letter not found"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		go services.DispatchNotifications(app)
+
+		c.JSON(http.StatusAccepted, gin.H{"message": "Notification re-queued", "id": id})
+	}
+}
+
+// ReplayAllDeadLetters re-queues every dead-lettered notification
+func ReplayAllDeadLetters(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ids []uint
+		if err := app.DB.Model(&models.NotificationOutbox{}).Where("status = ?", services.OutboxDead).Pluck("id", &ids).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		replayed := 0
+		for _, id := range ids {
+			if err := services.ReplayDeadLetter(app, id); err == nil {
+				replayed++
+			}
+		}
+
+		if replayed > 0 {
+			go services.DispatchNotifications(app)
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{"message": "Dead letters re-queued", "replayed": replayed})
+	}
+}
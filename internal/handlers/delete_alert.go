@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"gorm.io/gorm"
+)
+
+// DeleteAlert removes one of the current user's alerts along with its webhooks
+// and any matches still waiting for a digest. Notifications already sent stay
+// in the history; queued ones are skipped by the dispatcher.
+func DeleteAlert(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		alert, ok := loadOwnedAlert(c, app)
+		if !ok {
+			return
+		}
+
+		err := app.DB.Transaction(func(tx *gorm.DB) error {
+			webhookIDs := tx.Model(&models.AlertWebhook{}).Select("id").Where("alert_id = ?", alert.ID)
+			if err := tx.Where("webhook_id IN (?)", webhookIDs).Delete(&models.WebhookDelivery{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("alert_id = ?", alert.ID).Delete(&models.AlertWebhook{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("alert_id = ? AND digest_id IS NULL", alert.ID).Delete(&models.DigestItem{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&alert).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+)
+
+// DeleteSource soft-deletes a news source. Its articles stay in the database
+// and keep their source_id, but the source is no longer listed or monitored.
+func DeleteSource(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		source, ok := loadSource(c, app)
+		if !ok {
+			return
+		}
+
+		if err := app.DB.Delete(&source).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
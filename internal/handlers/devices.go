@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"gorm.io/gorm/clause"
+)
+
+// RegisterDeviceRequest represents the request body for registering a device for push notifications
+type RegisterDeviceRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Platform string `json:"platform"`
+}
+
+// RegisterDevice stores an FCM registration token for the current user.
+// Re-registering a token moves it to whoever registered it last.
+func RegisterDevice(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, _ := c.Get("user")
+		currentUser := user.(models.User)
+
+		var req RegisterDeviceRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		device := models.DeviceToken{
+			UserID:   currentUser.ID,
+			Token:    strings.TrimSpace(req.Token),
+			Platform: strings.ToLower(strings.TrimSpace(req.Platform)),
+		}
+
+		err := app.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "token"}},
+			DoUpdates: clause.AssignmentColumns([]string{"user_id", "platform"}),
+		}).Create(&device).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, device)
+	}
+}
+
+// GetDevices lists the current user's registered devices
+func GetDevices(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, _ := c.Get("user")
+		currentUser := user.(models.User)
+
+		var devices []models.DeviceToken
+		if err := app.DB.Where("user_id = ?", currentUser.ID).Find(&devices).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, devices)
+	}
+}
+
+// DeleteDevice unregisters one of the current user's devices
+func DeleteDevice(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, _ := c.Get("user")
+		currentUser := user.(models.User)
+
+		id, ok := parseIDParam(c)
+		if !ok {
+			return
+		}
+
+		result := app.DB.Where("id = ? AND user_id = ?", id, currentUser.ID).Delete(&models.DeviceToken{})
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+			return
+		}
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"github.com/mrrobotisreal/rss_today_api/internal/services"
+)
+
+// DiscoverFeeds lists the feeds published by a website, e.g. /api/sources/discover?url=https://bbc.com
+func DiscoverFeeds(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		siteURL := c.Query("url")
+		if siteURL == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "url query parameter is required"})
+			return
+		}
+
+		candidates, err := services.DiscoverFeeds(app, siteURL)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if candidates == nil {
+			candidates = []services.FeedCandidate{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"url":        siteURL,
+			"candidates": candidates,
+		})
+	}
+}
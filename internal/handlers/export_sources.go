@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"github.com/mrrobotisreal/rss_today_api/internal/services"
+)
+
+// ExportSources returns the active news sources as an OPML 2.0 document.
+// ?scope=mine limits the export to sources the current user's alerts watch.
+func ExportSources(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, _ := c.Get("user")
+		currentUser := user.(models.User)
+
+		title := "RSS Today sources"
+		query := app.DB.Where("active = ?", true).Order("category, name")
+
+		if c.Query("scope") == "mine" {
+			title = "RSS Today subscriptions for " + currentUser.Email
+
+			var alerts []models.UserAlert
+			if err := app.DB.Where("user_id = ? AND active = ?", currentUser.ID, true).Find(&alerts).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			// An alert with no source filter watches every source
+			var sourceIDs pq.Int64Array
+			watchesAll := false
+			for _, alert := range alerts {
+				if len(alert.SourceIDs) == 0 {
+					watchesAll = true
+					break
+				}
+				sourceIDs = append(sourceIDs, alert.SourceIDs...)
+			}
+
+			if !watchesAll {
+				if len(sourceIDs) == 0 {
+					// No alerts at all: export an empty document
+					sourceIDs = pq.Int64Array{0}
+				}
+				query = query.Where("id = ANY(?)", sourceIDs)
+			}
+		}
+
+		var sources []models.NewsSource
+		if err := query.Find(&sources).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		document, err := services.BuildOPML(title, sources)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Header("Content-Disposition", `attachment; filename="sources.opml"`)
+		c.Data(http.StatusOK, "text/x-opml; charset=utf-8", document)
+	}
+}
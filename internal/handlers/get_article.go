@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"github.com/mrrobotisreal/rss_today_api/internal/services"
+	"gorm.io/gorm"
+)
+
+// GetArticle returns one article with its source, the current user's alerts
+// that matched it when it arrived (matched_alerts) and that match it as they
+// are now (matching_alerts), and other outlets' coverage of the same story
+// (related, each with the shared_keywords that tie it to this one)
+func GetArticle(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, _ := c.Get("user")
+		currentUser := user.(models.User)
+
+		id, ok := parseIDParam(c)
+		if !ok {
+			return
+		}
+
+		detail, err := services.GetArticleDetail(app, id, currentUser.ID)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, detail)
+	}
+}
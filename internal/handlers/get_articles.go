@@ -4,41 +4,259 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"github.com/mrrobotisreal/rss_today_api/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
+	"gorm.io/gorm"
 )
 
+// Article list sort orders
+const (
+	sortNewest    = "newest"
+	sortOldest    = "oldest"
+	sortRelevance = "relevance" // Only with ?q=
+)
+
+// Article page sizes
+const (
+	defaultArticleLimit = 50
+	maxArticleLimit     = 200
+)
+
+// envelopeParams are the GetArticles parameters that came with the
+// {"articles", "next_cursor"} envelope; requests without any of them still
+// get a bare array, as they always have
+var envelopeParams = []string{"cursor", "q", "since", "until", "sort", "collapse"}
+
+// GetArticles lists stored articles as {"articles": [...], "next_cursor": ...}.
+// Supports ?limit= (default 50, max 200), ?cursor= from the previous page's
+// next_cursor, ?keywords= (comma-separated, any of), ?source_id= (repeated or
+// comma-separated), ?since= and ?until= on the publication date,
+// ?sort=newest|oldest|relevance, and ?collapse=true to list near-duplicate
+// copies of a story only once.
+//
+// Requests using none of envelopeParams get the newest articles as a bare
+// array, for clients written before pagination; ?sort=newest asks for the
+// same first page in the envelope.
+//
+// ?q= runs a full-text search over titles, descriptions and extracted article
+// text (websearch syntax: words, "phrases", OR, -exclusions); results then
+// include a rank and highlighted title_highlight and snippet, and sort by
+// relevance by default. Article bodies are only returned by GetArticle.
 func GetArticles(app *models.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		keywords := c.Query("keywords")
-		sourceID := c.Query("source_id")
-		limitStr := c.DefaultQuery("limit", "50")
+		search := strings.TrimSpace(c.Query("q"))
 
-		limit, _ := strconv.Atoi(limitStr)
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultArticleLimit)))
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		if limit > maxArticleLimit {
+			limit = maxArticleLimit
+		}
 
-		query := app.DB.Model(&models.Article{}).Preload("Source")
+		sort := c.Query("sort")
+		if sort == "" {
+			sort = sortNewest
+			if search != "" {
+				sort = sortRelevance
+			}
+		}
+		switch sort {
+		case sortNewest, sortOldest:
+		case sortRelevance:
+			if search == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "sort=relevance requires q"})
+				return
+			}
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be newest, oldest or relevance"})
+			return
+		}
 
-		if keywords != "" {
+		query := app.DB.Model(&models.Article{})
+
+		if keywords := c.Query("keywords"); keywords != "" {
 			keywordList := strings.Split(keywords, ",")
 			for i, keyword := range keywordList {
 				keywordList[i] = strings.TrimSpace(keyword)
 			}
-			query = query.Where("keywords && ?", pq.Array(keywordList))
+			query = query.Where("articles.keywords && ?", pq.Array(keywordList))
+		}
+
+		sourceIDs, ok := parseSourceIDs(c)
+		if !ok {
+			return
+		}
+		if len(sourceIDs) > 0 {
+			query = query.Where("articles.source_id IN ?", sourceIDs)
+		}
+
+		since, ok := parseTimeQuery(c, "since")
+		if !ok {
+			return
+		}
+		if since != nil {
+			query = query.Where("articles.pub_date >= ?", *since)
+		}
+		until, ok := parseTimeQuery(c, "until")
+		if !ok {
+			return
+		}
+		if until != nil {
+			query = query.Where("articles.pub_date < ?", *until)
+		}
+
+		if c.Query("collapse") == "true" {
+			// Show each story once, as its first report; its duplicate_count says how many more there are
+			query = query.Where("(articles.cluster_id IS NULL OR articles.cluster_id = articles.id)")
+		}
+
+		if search != "" {
+			query = services.ArticleSearchCondition(query, search)
+		}
+
+		if cursor := c.Query("cursor"); cursor != "" {
+			if query, err = applyArticleCursor(query, cursor, sort, search); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+				return
+			}
+		}
+
+		switch sort {
+		case sortRelevance:
+			query = services.OrderByRelevance(query, search)
+		case sortOldest:
+			query = query.Order("articles.pub_date ASC, articles.id ASC")
+		default:
+			query = query.Order("articles.pub_date DESC, articles.id DESC")
 		}
+		query = query.Limit(limit)
 
-		if sourceID != "" {
-			query = query.Where("source_id = ?", sourceID)
+		response := gin.H{"next_cursor": nil}
+
+		if search != "" {
+			hits, err := services.SearchArticles(app, query, search)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			response["articles"] = hits
+			if len(hits) == limit {
+				last := hits[len(hits)-1]
+				response["next_cursor"] = articleCursor(sort, last.Article, last.Rank)
+			}
+			c.JSON(http.StatusOK, response)
+			return
 		}
 
+		// Unscoped so articles from soft-deleted sources still carry their source.
+		// Full bodies are left to the article detail endpoint to keep pages small.
+		query = query.Omit("content").Preload("Source", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		})
+
 		var articles []models.Article
-		if err := query.Order("pub_date DESC").Limit(limit).Find(&articles).Error; err != nil {
+		if err := query.Find(&articles).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusOK, articles)
+		if !wantsEnvelope(c) {
+			c.JSON(http.StatusOK, articles)
+			return
+		}
+
+		response["articles"] = articles
+		if len(articles) == limit {
+			response["next_cursor"] = articleCursor(sort, articles[len(articles)-1], 0)
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// wantsEnvelope reports whether the request uses any of envelopeParams
+func wantsEnvelope(c *gin.Context) bool {
+	for _, param := range envelopeParams {
+		if _, ok := c.GetQuery(param); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSourceIDs reads ?source_id=, which may be repeated or comma-separated,
+// writing a 400 response if any value is not a valid ID
+func parseSourceIDs(c *gin.Context) ([]uint, bool) {
+	var ids []uint
+	for _, value := range c.QueryArray("source_id") {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			id, err := strconv.ParseUint(part, 10, 64)
+			if err != nil || id == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "source_id must be a list of positive integers"})
+				return nil, false
+			}
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids, true
+}
+
+// articleCursor encodes the position of the last article on a page. The sort
+// order is included so a cursor can't be replayed against a different one.
+func articleCursor(sort string, article models.Article, rank float64) string {
+	pubDate := article.PubDate.UTC().Format(time.RFC3339Nano)
+	id := strconv.FormatUint(uint64(article.ID), 10)
+	if sort == sortRelevance {
+		// ts_rank_cd is a real, so 32-bit formatting round-trips it exactly
+		return encodeCursor(sort, strconv.FormatFloat(rank, 'g', -1, 32), pubDate, id)
+	}
+	return encodeCursor(sort, pubDate, id)
+}
+
+// applyArticleCursor restricts query to the articles after cursor in sort order
+func applyArticleCursor(query *gorm.DB, cursor, sort, search string) (*gorm.DB, error) {
+	n := 3
+	if sort == sortRelevance {
+		n = 4
+	}
+	values, err := decodeCursor(cursor, n)
+	if err != nil {
+		return nil, err
+	}
+	if values[0] != sort {
+		return nil, errInvalidCursor
+	}
+
+	pubDate, err := time.Parse(time.RFC3339Nano, values[n-2])
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	id, err := strconv.ParseUint(values[n-1], 10, 64)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	switch sort {
+	case sortRelevance:
+		rank, err := strconv.ParseFloat(values[1], 32)
+		if err != nil {
+			return nil, errInvalidCursor
+		}
+		return services.RelevanceAfter(query, search, rank, pubDate, uint(id)), nil
+	case sortOldest:
+		return query.Where("(articles.pub_date, articles.id) > (?, ?)", pubDate, id), nil
+	default:
+		return query.Where("(articles.pub_date, articles.id) < (?, ?)", pubDate, id), nil
 	}
 }
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"github.com/mrrobotisreal/rss_today_api/internal/services"
+)
+
+// GetMonitorLocks shows which instances currently hold the monitoring cycle and source locks
+func GetMonitorLocks(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locks, err := services.ListAdvisoryLocks(app)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		cycleRunning := false
+		for _, lock := range locks {
+			if lock.Kind == "cycle" {
+				cycleRunning = true
+				break
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"instance_id":   services.InstanceID,
+			"cycle_running": cycleRunning,
+			"locks":         locks,
+		})
+	}
+}
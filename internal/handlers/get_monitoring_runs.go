@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"gorm.io/gorm"
+)
+
+// GetMonitoringRuns lists recent monitoring runs, newest first.
+// Supports ?limit= (default 20, max 100), ?status= and ?trigger=.
+func GetMonitoringRuns(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		if limit > 100 {
+			limit = 100
+		}
+
+		query := app.DB.Model(&models.MonitoringRun{})
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+		if trigger := c.Query("trigger"); trigger != "" {
+			query = query.Where("trigger = ?", trigger)
+		}
+
+		var runs []models.MonitoringRun
+		if err := query.Order("started_at DESC").Limit(limit).Find(&runs).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		app.Mu.RLock()
+		lastRun := app.LastRun
+		app.Mu.RUnlock()
+
+		response := gin.H{"runs": runs}
+		if !lastRun.IsZero() {
+			// Last cycle started by this instance; runs may come from any instance
+			response["last_run_at"] = lastRun
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// GetMonitoringRun returns one run including its per-source errors
+func GetMonitoringRun(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseIDParam(c)
+		if !ok {
+			return
+		}
+
+		var run models.MonitoringRun
+		if err := app.DB.Preload("SourceErrors").First(&run, id).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Monitoring run not found"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, run)
+	}
+}
@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"github.com/mrrobotisreal/rss_today_api/internal/services"
+)
+
+func GetSourceHealth(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		source, ok := loadSource(c, app)
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, services.GetSourceHealth(source))
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"gorm.io/gorm"
 )
 
 func GetUserAlerts(app *models.App) gin.HandlerFunc {
@@ -21,3 +22,28 @@ func GetUserAlerts(app *models.App) gin.HandlerFunc {
 		c.JSON(http.StatusOK, alerts)
 	}
 }
+
+// loadOwnedAlert fetches the :id alert if it belongs to the current user,
+// writing a 404 otherwise so other users' alert IDs aren't revealed
+func loadOwnedAlert(c *gin.Context, app *models.App) (models.UserAlert, bool) {
+	var alert models.UserAlert
+
+	user, _ := c.Get("user")
+	currentUser := user.(models.User)
+
+	id, ok := parseIDParam(c)
+	if !ok {
+		return alert, false
+	}
+
+	if err := app.DB.Where("id = ? AND user_id = ?", id, currentUser.ID).First(&alert).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return alert, false
+	}
+
+	return alert, true
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"github.com/mrrobotisreal/rss_today_api/internal/services"
+)
+
+// maxOPMLSize caps uploaded OPML documents at 5 MiB
+const maxOPMLSize = 5 << 20
+
+// importValidationWorkers limits how many feeds are fetched at once while validating an import
+const importValidationWorkers = 8
+
+// InvalidOPMLFeed is a feed from an import that can't be added, with the reason why
+type InvalidOPMLFeed struct {
+	services.OPMLFeed
+	Reason string `json:"reason"`
+}
+
+// ImportSourcesResponse reports what an OPML import did (or would do, on a dry run)
+type ImportSourcesResponse struct {
+	DryRun    bool                `json:"dry_run"`
+	Total     int                 `json:"total"`
+	New       []services.OPMLFeed `json:"new"`
+	Duplicate []services.OPMLFeed `json:"duplicate"`
+	Invalid   []InvalidOPMLFeed   `json:"invalid"`
+	Created   []models.NewsSource `json:"created,omitempty"`
+}
+
+// ImportSources adds the feeds in an OPML document as news sources.
+// The document is sent as the raw request body or as a multipart "file" field.
+// ?dry_run=true reports new/duplicate/invalid feeds without saving anything, and
+// ?validate=false skips fetching each new feed to check that it parses.
+func ImportSources(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		dryRun := c.Query("dry_run") == "true"
+		validate := c.DefaultQuery("validate", "true") != "false"
+
+		body, err := readOPMLUpload(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer body.Close()
+
+		// Read one byte past the limit so an oversized file is rejected rather than cut short
+		data, err := io.ReadAll(io.LimitReader(body, maxOPMLSize+1))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(data) > maxOPMLSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "OPML file must be at most 5 MiB"})
+			return
+		}
+
+		feeds, err := services.ParseOPML(bytes.NewReader(data))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		response := ImportSourcesResponse{
+			DryRun:    dryRun,
+			Total:     len(feeds),
+			New:       []services.OPMLFeed{},
+			Duplicate: []services.OPMLFeed{},
+			Invalid:   []InvalidOPMLFeed{},
+		}
+
+		// Sort feeds into new/duplicate/invalid without touching the network first
+		seen := map[string]bool{}
+		var candidates []services.OPMLFeed
+		for _, feed := range feeds {
+			if reason := checkFeedURL(feed.XMLURL); reason != "" {
+				response.Invalid = append(response.Invalid, InvalidOPMLFeed{OPMLFeed: feed, Reason: reason})
+				continue
+			}
+
+			key := models.NormalizeFeedURL(feed.XMLURL)
+			if seen[key] {
+				response.Duplicate = append(response.Duplicate, feed)
+				continue
+			}
+			seen[key] = true
+
+			taken, err := rssURLTaken(app, feed.XMLURL, 0)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if taken {
+				response.Duplicate = append(response.Duplicate, feed)
+				continue
+			}
+
+			candidates = append(candidates, feed)
+		}
+
+		if validate {
+			candidates = validateOPMLFeeds(app, candidates, &response)
+		}
+		response.New = append(response.New, candidates...)
+
+		if dryRun {
+			c.JSON(http.StatusOK, response)
+			return
+		}
+
+		for _, feed := range candidates {
+			source := models.NewsSource{
+				Name:     feed.Title,
+				URL:      feed.HTMLURL,
+				RSSURL:   feed.XMLURL,
+				Category: feed.Category,
+				Active:   true,
+			}
+			if source.Name == "" {
+				source.Name = feed.XMLURL
+			}
+
+			if err := app.DB.Create(&source).Error; err != nil {
+				if isUniqueViolation(err) {
+					// Added by someone else since we checked
+					response.Duplicate = append(response.Duplicate, feed)
+					continue
+				}
+				response.Invalid = append(response.Invalid, InvalidOPMLFeed{OPMLFeed: feed, Reason: "Failed to save: " + err.Error()})
+				continue
+			}
+			response.Created = append(response.Created, source)
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// readOPMLUpload returns the OPML document from either a multipart "file" field or the raw body
+func readOPMLUpload(c *gin.Context) (io.ReadCloser, error) {
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return nil, err
+		}
+		return fileHeader.Open()
+	}
+	return c.Request.Body, nil
+}
+
+// checkFeedURL returns why rawURL can't be used as a feed URL, or "" if it looks fine
+func checkFeedURL(rawURL string) string {
+	if rawURL == "" {
+		return "missing xmlUrl"
+	}
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "malformed URL"
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return "URL must use http or https"
+	}
+	if parsedURL.Host == "" {
+		return "URL must include a host"
+	}
+	return ""
+}
+
+// validateOPMLFeeds fetches each feed, moving the ones that don't parse into response.Invalid
+func validateOPMLFeeds(app *models.App, feeds []services.OPMLFeed, response *ImportSourcesResponse) []services.OPMLFeed {
+	errs := make([]error, len(feeds))
+	semaphore := make(chan struct{}, importValidationWorkers)
+
+	var wg sync.WaitGroup
+	for i, feed := range feeds {
+		wg.Add(1)
+		go func(i int, feed services.OPMLFeed) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			_, errs[i] = services.ValidateFeedURL(app, feed.XMLURL)
+		}(i, feed)
+	}
+	wg.Wait()
+
+	var valid []services.OPMLFeed
+	for i, feed := range feeds {
+		if errs[i] != nil {
+			response.Invalid = append(response.Invalid, InvalidOPMLFeed{OPMLFeed: feed, Reason: errs[i].Error()})
+			continue
+		}
+		valid = append(valid, feed)
+	}
+	return valid
+}
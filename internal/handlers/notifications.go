@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"github.com/mrrobotisreal/rss_today_api/internal/services"
+)
+
+// NotificationIDsRequest names inbox items to act on
+type NotificationIDsRequest struct {
+	IDs []uint `json:"ids"`
+}
+
+// MarkReadRequest marks inbox items read, either by ID or all at once
+type MarkReadRequest struct {
+	IDs     []uint     `json:"ids"`
+	All     bool       `json:"all"`      // Mark every unread item instead of IDs
+	AlertID uint       `json:"alert_id"` // With all, only this alert's items
+	Before  *time.Time `json:"before"`   // With all, only items created before this time
+}
+
+// GetNotifications lists the current user's notification inbox, newest first.
+// Supports ?limit= (default 20, max 100), ?cursor= from the previous page's
+// next_cursor, ?alert_id=, ?since= and ?until=, ?unread=true and
+// ?include_dismissed=true.
+func GetNotifications(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, _ := c.Get("user")
+		currentUser := user.(models.User)
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		if limit > 100 {
+			limit = 100
+		}
+
+		filter := services.InboxFilter{
+			UserID:           currentUser.ID,
+			UnreadOnly:       c.Query("unread") == "true",
+			IncludeDismissed: c.Query("include_dismissed") == "true",
+			Limit:            limit,
+		}
+
+		var ok bool
+		if filter.AlertID, ok = parseUintQuery(c, "alert_id"); !ok {
+			return
+		}
+		if filter.Since, ok = parseTimeQuery(c, "since"); !ok {
+			return
+		}
+		if filter.Until, ok = parseTimeQuery(c, "until"); !ok {
+			return
+		}
+
+		if cursor := c.Query("cursor"); cursor != "" {
+			if filter.Before, err = decodeInboxCursor(cursor); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+				return
+			}
+		}
+
+		items, err := services.ListInbox(app, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		unread, err := services.CountUnread(app, currentUser.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		response := gin.H{
+			"notifications": items,
+			"unread_count":  unread,
+			"next_cursor":   nil,
+		}
+		if len(items) == limit {
+			last := items[len(items)-1]
+			response["next_cursor"] = encodeCursor(last.CreatedAt.UTC().Format(time.RFC3339Nano), strconv.FormatUint(uint64(last.ID), 10))
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// decodeInboxCursor reads a next_cursor written by GetNotifications
+func decodeInboxCursor(cursor string) (*services.InboxCursor, error) {
+	values, err := decodeCursor(cursor, 2)
+	if err != nil {
+		return nil, err
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, values[0])
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	id, err := strconv.ParseUint(values[1], 10, 64)
+	if err != nil || id == 0 {
+		return nil, errInvalidCursor
+	}
+	return &services.InboxCursor{CreatedAt: createdAt, ID: uint(id)}, nil
+}
+
+// MarkNotificationsRead marks inbox items as read, by ID or with {"all": true}
+func MarkNotificationsRead(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, _ := c.Get("user")
+		currentUser := user.(models.User)
+
+		var req MarkReadRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.All == (len(req.IDs) > 0) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Provide either ids or all"})
+			return
+		}
+
+		var updated int64
+		var err error
+		if req.All {
+			updated, err = services.MarkAllNotificationsRead(app, currentUser.ID, req.AlertID, req.Before)
+		} else {
+			updated, err = services.MarkNotificationsRead(app, currentUser.ID, req.IDs)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"updated": updated})
+	}
+}
+
+// MarkNotificationsUnread marks inbox items as unread again
+func MarkNotificationsUnread(app *models.App) gin.HandlerFunc {
+	return updateNotifications(app, services.MarkNotificationsUnread)
+}
+
+// DismissNotifications removes inbox items from the default listing
+func DismissNotifications(app *models.App) gin.HandlerFunc {
+	return updateNotifications(app, services.DismissNotifications)
+}
+
+// updateNotifications binds {"ids": [...]} and applies update to the current user's inbox items
+func updateNotifications(app *models.App, update func(*models.App, uint, []uint) (int64, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, _ := c.Get("user")
+		currentUser := user.(models.User)
+
+		var req NotificationIDsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(req.IDs) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ids is required"})
+			return
+		}
+
+		updated, err := update(app, currentUser.ID, req.IDs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"updated": updated})
+	}
+}
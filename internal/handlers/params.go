@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseIDParam reads the :id path parameter, writing a 400 response if it is not a valid ID
+func parseIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// parseTimeQuery reads an optional RFC 3339 time or YYYY-MM-DD date (as UTC
+// midnight) from the query string, writing a 400 response if it is malformed
+func parseTimeQuery(c *gin.Context, name string) (*time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return &parsed, true
+		}
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be an RFC 3339 time or a YYYY-MM-DD date"})
+	return nil, false
+}
+
+// parseUintQuery reads an optional positive integer ID from the query string,
+// returning 0 if it is absent and writing a 400 response if it is malformed
+func parseUintQuery(c *gin.Context, name string) (uint, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, true
+	}
+
+	parsed, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || parsed == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be a positive integer"})
+		return 0, false
+	}
+	return uint(parsed), true
+}
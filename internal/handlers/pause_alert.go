@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+)
+
+// SnoozeRequest silences an alert either until a time or for a number of minutes
+type SnoozeRequest struct {
+	Until   *time.Time `json:"until"`
+	Minutes int        `json:"minutes"`
+}
+
+// PauseAlert turns an alert off until it is resumed
+func PauseAlert(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setAlertFields(c, app, map[string]interface{}{"active": false})
+	}
+}
+
+// ResumeAlert turns a paused alert back on
+func ResumeAlert(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setAlertFields(c, app, map[string]interface{}{"active": true})
+	}
+}
+
+// SnoozeAlert ignores an alert's matches until the given time, after which it
+// picks up again on its own
+func SnoozeAlert(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req SnoozeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var until time.Time
+		switch {
+		case req.Until != nil && req.Minutes != 0:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Provide either until or minutes, not both"})
+			return
+		case req.Until != nil:
+			until = *req.Until
+		case req.Minutes > 0:
+			until = time.Now().Add(time.Duration(req.Minutes) * time.Minute)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until or a positive minutes is required"})
+			return
+		}
+
+		if !until.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until must be in the future"})
+			return
+		}
+
+		setAlertFields(c, app, map[string]interface{}{"snoozed_until": until})
+	}
+}
+
+// UnsnoozeAlert clears an alert's snooze right away
+func UnsnoozeAlert(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setAlertFields(c, app, map[string]interface{}{"snoozed_until": nil})
+	}
+}
+
+// setAlertFields applies updates to the current user's :id alert and responds with the result
+func setAlertFields(c *gin.Context, app *models.App, updates map[string]interface{}) {
+	alert, ok := loadOwnedAlert(c, app)
+	if !ok {
+		return
+	}
+
+	if err := app.DB.Model(&alert).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := app.DB.First(&alert, alert.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
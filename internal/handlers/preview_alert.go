@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"github.com/mrrobotisreal/rss_today_api/internal/services"
+)
+
+// PreviewAlert dry-runs an unsaved alert definition against recently stored
+// articles, so users can tune it before saving. ?days= sets the window
+// (default 7) and ?samples= how many matching articles to return (default 10).
+func PreviewAlert(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, _ := c.Get("user")
+		currentUser := user.(models.User)
+
+		days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+		if err != nil || days < 1 || days > services.PreviewMaxDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and " + strconv.Itoa(services.PreviewMaxDays)})
+			return
+		}
+
+		samples, err := strconv.Atoi(c.DefaultQuery("samples", "10"))
+		if err != nil || samples < 0 || samples > services.PreviewMaxSamples {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "samples must be between 0 and " + strconv.Itoa(services.PreviewMaxSamples)})
+			return
+		}
+
+		var alert models.UserAlert
+		if err := c.ShouldBindJSON(&alert); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !validateAlert(c, &alert) {
+			return
+		}
+		if alert.Type == services.AlertTypeSpike {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Preview is only available for match alerts"})
+			return
+		}
+
+		preview, err := services.PreviewAlert(app, alert, days, samples, services.UserLocation(currentUser))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, preview)
+	}
+}
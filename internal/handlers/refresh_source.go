@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"github.com/mrrobotisreal/rss_today_api/internal/services"
+)
+
+// RefreshSource fetches one source immediately. With ?sync=true it waits and
+// returns the parsed/new/matched counts; otherwise it returns a run ID to poll
+// at /api/monitor/runs/:id.
+func RefreshSource(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		source, ok := loadSource(c, app)
+		if !ok {
+			return
+		}
+
+		if c.Query("sync") != "true" {
+			run, err := services.StartSourceRefresh(app, source)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			c.JSON(http.StatusAccepted, gin.H{
+				"message": "Refresh started",
+				"run_id":  run.ID,
+				"run":     run,
+			})
+			return
+		}
+
+		run, err := services.RefreshSource(app, source)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		response := gin.H{
+			"run_id":          run.ID,
+			"source_id":       source.ID,
+			"articles_parsed": run.ArticlesParsed,
+			"articles_new":    run.ArticlesNew,
+			"alerts_matched":  run.AlertsMatched,
+		}
+
+		status := http.StatusOK
+		if len(run.SourceErrors) > 0 {
+			response["error"] = run.SourceErrors[0].Error
+			if run.SourceErrors[0].Skipped {
+				// Someone else is on it or the host asked us to wait; try again later
+				status = http.StatusConflict
+			} else {
+				status = http.StatusBadGateway
+			}
+		}
+
+		c.JSON(status, response)
+	}
+}
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -11,12 +10,16 @@ import (
 
 func TriggerMonitoring(app *models.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		go func() {
-			if err := services.MonitorAllSources(app); err != nil {
-				log.Printf("Error in manual monitoring: %v", err)
-			}
-		}()
+		run, err := services.StartMonitoringRun(app, services.TriggerManual)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 
-		c.JSON(http.StatusOK, gin.H{"message": "Monitoring triggered successfully"})
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "Monitoring triggered successfully",
+			"run_id":  run.ID,
+			"run":     run,
+		})
 	}
 }
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+)
+
+// AlertRequest holds every editable field of an alert, for full replacement with PUT
+type AlertRequest struct {
+	Keywords            []string   `json:"keywords"`
+	Query               string     `json:"query"`
+	SourceIDs           []int64    `json:"source_ids"`
+	NotificationMethods []string   `json:"notification_methods"`
+	Active              *bool      `json:"active"`
+	SnoozedUntil        *time.Time `json:"snoozed_until"`
+	DeliveryMode        string     `json:"delivery_mode"`
+	DigestTime          string     `json:"digest_time"`
+	DigestWeekday       string     `json:"digest_weekday"`
+	Type                string     `json:"type"`
+	SpikeWindowMinutes  int        `json:"spike_window_minutes"`
+	SpikeBaselineHours  int        `json:"spike_baseline_hours"`
+	SpikeFactor         float64    `json:"spike_factor"`
+	SpikeMinArticles    int        `json:"spike_min_articles"`
+}
+
+// PatchAlertRequest represents a partial update to an alert; omitted fields are left unchanged
+type PatchAlertRequest struct {
+	Keywords            *[]string  `json:"keywords"`
+	Query               *string    `json:"query"`
+	SourceIDs           *[]int64   `json:"source_ids"`
+	NotificationMethods *[]string  `json:"notification_methods"`
+	Active              *bool      `json:"active"`
+	SnoozedUntil        *time.Time `json:"snoozed_until"`
+	ClearSnooze         bool       `json:"clear_snooze"` // Set to true to remove snoozed_until
+	DeliveryMode        *string    `json:"delivery_mode"`
+	DigestTime          *string    `json:"digest_time"`
+	DigestWeekday       *string    `json:"digest_weekday"`
+	Type                *string    `json:"type"`
+	SpikeWindowMinutes  *int       `json:"spike_window_minutes"`
+	SpikeBaselineHours  *int       `json:"spike_baseline_hours"`
+	SpikeFactor         *float64   `json:"spike_factor"`
+	SpikeMinArticles    *int       `json:"spike_min_articles"`
+}
+
+// UpdateAlert replaces all editable fields of one of the current user's alerts
+func UpdateAlert(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		alert, ok := loadOwnedAlert(c, app)
+		if !ok {
+			return
+		}
+
+		var req AlertRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		active := true
+		if req.Active != nil {
+			active = *req.Active
+		}
+
+		patch := PatchAlertRequest{
+			Keywords:            &req.Keywords,
+			Query:               &req.Query,
+			SourceIDs:           &req.SourceIDs,
+			NotificationMethods: &req.NotificationMethods,
+			Active:              &active,
+			SnoozedUntil:        req.SnoozedUntil,
+			ClearSnooze:         req.SnoozedUntil == nil,
+			DeliveryMode:        &req.DeliveryMode,
+			DigestTime:          &req.DigestTime,
+			DigestWeekday:       &req.DigestWeekday,
+			Type:                &req.Type,
+			SpikeWindowMinutes:  &req.SpikeWindowMinutes,
+			SpikeBaselineHours:  &req.SpikeBaselineHours,
+			SpikeFactor:         &req.SpikeFactor,
+			SpikeMinArticles:    &req.SpikeMinArticles,
+		}
+		applyAlertPatch(c, app, alert, patch)
+	}
+}
+
+// PatchAlert updates only the fields present in the request, e.g. {"delivery_mode": "daily"}
+func PatchAlert(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		alert, ok := loadOwnedAlert(c, app)
+		if !ok {
+			return
+		}
+
+		var req PatchAlertRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		applyAlertPatch(c, app, alert, req)
+	}
+}
+
+func applyAlertPatch(c *gin.Context, app *models.App, alert models.UserAlert, req PatchAlertRequest) {
+	// Apply the changes to a copy first so the result can be validated as a whole
+	updated := alert
+	if req.Keywords != nil {
+		updated.Keywords = pq.StringArray(*req.Keywords)
+	}
+	if req.Query != nil {
+		updated.Query = *req.Query
+	}
+	if req.SourceIDs != nil {
+		updated.SourceIDs = pq.Int64Array(*req.SourceIDs)
+	}
+	if req.NotificationMethods != nil {
+		updated.NotificationMethods = pq.StringArray(*req.NotificationMethods)
+	}
+	if req.Active != nil {
+		updated.Active = *req.Active
+	}
+	if req.SnoozedUntil != nil {
+		updated.SnoozedUntil = req.SnoozedUntil
+	} else if req.ClearSnooze {
+		updated.SnoozedUntil = nil
+	}
+	if req.DeliveryMode != nil {
+		updated.DeliveryMode = *req.DeliveryMode
+	}
+	if req.DigestTime != nil {
+		updated.DigestTime = *req.DigestTime
+	}
+	if req.DigestWeekday != nil {
+		updated.DigestWeekday = *req.DigestWeekday
+	}
+	if req.Type != nil {
+		updated.Type = *req.Type
+	}
+	if req.SpikeWindowMinutes != nil {
+		updated.SpikeWindowMinutes = *req.SpikeWindowMinutes
+	}
+	if req.SpikeBaselineHours != nil {
+		updated.SpikeBaselineHours = *req.SpikeBaselineHours
+	}
+	if req.SpikeFactor != nil {
+		updated.SpikeFactor = *req.SpikeFactor
+	}
+	if req.SpikeMinArticles != nil {
+		updated.SpikeMinArticles = *req.SpikeMinArticles
+	}
+
+	if !validateAlert(c, &updated) {
+		return
+	}
+
+	updates := map[string]interface{}{
+		"keywords":             updated.Keywords,
+		"query":                updated.Query,
+		"source_ids":           updated.SourceIDs,
+		"notification_methods": updated.NotificationMethods,
+		"active":               updated.Active,
+		"snoozed_until":        updated.SnoozedUntil,
+		"delivery_mode":        updated.DeliveryMode,
+		"digest_time":          updated.DigestTime,
+		"digest_weekday":       updated.DigestWeekday,
+		"type":                 updated.Type,
+		"spike_window_minutes": updated.SpikeWindowMinutes,
+		"spike_baseline_hours": updated.SpikeBaselineHours,
+		"spike_factor":         updated.SpikeFactor,
+		"spike_min_articles":   updated.SpikeMinArticles,
+	}
+	if err := app.DB.Model(&alert).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := app.DB.First(&alert, alert.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
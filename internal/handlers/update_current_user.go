@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"github.com/mrrobotisreal/rss_today_api/internal/services"
+)
+
+type UpdateUserRequest struct {
+	DisplayName *string `json:"display_name"`
+	Timezone    *string `json:"timezone"` // IANA zone, e.g. "Europe/Kyiv"
+}
+
+// UpdateCurrentUser changes the signed-in user's profile settings
+func UpdateCurrentUser(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, _ := c.Get("user")
+		currentUser := user.(models.User)
+
+		var req UpdateUserRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		updates := map[string]interface{}{}
+		if req.DisplayName != nil {
+			updates["display_name"] = *req.DisplayName
+		}
+		if req.Timezone != nil {
+			if err := services.ValidateTimezone(*req.Timezone); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			updates["timezone"] = *req.Timezone
+		}
+
+		if len(updates) > 0 {
+			if err := app.DB.Model(&currentUser).Updates(updates).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		if err := app.DB.First(&currentUser, currentUser.ID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, currentUser)
+	}
+}
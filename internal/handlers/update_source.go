@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"github.com/mrrobotisreal/rss_today_api/internal/services"
+	"gorm.io/gorm"
+)
+
+// PatchSourceRequest represents a partial update to a news source; omitted fields are left unchanged
+type PatchSourceRequest struct {
+	Name     *string `json:"name"`
+	URL      *string `json:"url"`
+	RSSURL   *string `json:"rss_url"`
+	Category *string `json:"category"`
+	Active   *bool   `json:"active"`
+
+	PollIntervalMinutes *int  `json:"poll_interval_minutes"`
+	AdaptivePolling     *bool `json:"adaptive_polling"`
+	ExtractContent      *bool `json:"extract_content"`
+}
+
+// UpdateSource replaces all editable fields of a news source
+func UpdateSource(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		source, ok := loadSource(c, app)
+		if !ok {
+			return
+		}
+
+		var req SourceRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if strings.TrimSpace(req.RSSURL) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rss_url is required"})
+			return
+		}
+
+		active := true
+		if req.Active != nil {
+			active = *req.Active
+		}
+
+		patch := PatchSourceRequest{
+			Name:     &req.Name,
+			URL:      &req.URL,
+			RSSURL:   &req.RSSURL,
+			Category: &req.Category,
+			Active:   &active,
+
+			PollIntervalMinutes: &req.PollIntervalMinutes,
+			AdaptivePolling:     &req.AdaptivePolling,
+			ExtractContent:      &req.ExtractContent,
+		}
+		applySourcePatch(c, app, source, patch)
+	}
+}
+
+// PatchSource updates only the fields present in the request, e.g. {"active": false}
+func PatchSource(app *models.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		source, ok := loadSource(c, app)
+		if !ok {
+			return
+		}
+
+		var req PatchSourceRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		applySourcePatch(c, app, source, req)
+	}
+}
+
+func applySourcePatch(c *gin.Context, app *models.App, source models.NewsSource, req PatchSourceRequest) {
+	updates := map[string]interface{}{}
+
+	if req.RSSURL != nil {
+		rssURL := strings.TrimSpace(*req.RSSURL)
+		if rssURL != source.RSSURL {
+			if taken, err := rssURLTaken(app, rssURL, source.ID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			} else if taken {
+				c.JSON(http.StatusConflict, gin.H{"error": "A source with this RSS URL already exists"})
+				return
+			}
+
+			// Only re-validate when the feed location actually changes
+			if _, err := services.ValidateFeedURL(app, rssURL); err != nil {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+				return
+			}
+			updates["rss_url"] = rssURL
+			updates["rss_url_key"] = models.NormalizeFeedURL(rssURL)
+			// The old feed's validators would make the first fetch of the new
+			// one conditional on, or compared against, a different document
+			updates["etag"] = ""
+			updates["last_modified"] = ""
+			updates["feed_hash"] = ""
+		}
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+			return
+		}
+		updates["name"] = name
+	}
+
+	if req.URL != nil {
+		updates["url"] = strings.TrimSpace(*req.URL)
+	}
+
+	if req.Category != nil {
+		updates["category"] = strings.Trim(strings.TrimSpace(*req.Category), "/")
+	}
+
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+
+	scheduleChanged := false
+	if req.PollIntervalMinutes != nil && *req.PollIntervalMinutes != source.PollIntervalMinutes {
+		if err := validatePollInterval(*req.PollIntervalMinutes); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updates["poll_interval_minutes"] = *req.PollIntervalMinutes
+		scheduleChanged = true
+	}
+
+	if req.AdaptivePolling != nil && *req.AdaptivePolling != source.AdaptivePolling {
+		updates["adaptive_polling"] = *req.AdaptivePolling
+		if !*req.AdaptivePolling {
+			updates["adaptive_interval_minutes"] = 0
+		}
+		scheduleChanged = true
+	}
+
+	if req.ExtractContent != nil {
+		updates["extract_content"] = *req.ExtractContent
+	}
+
+	// Let the scheduler pick up a new interval on its next tick, unless the source is backing off
+	if scheduleChanged && source.ConsecutiveFailures == 0 {
+		updates["next_fetch_at"] = nil
+	}
+
+	if len(updates) > 0 {
+		if err := app.DB.Model(&source).Updates(updates).Error; err != nil {
+			if isUniqueViolation(err) {
+				c.JSON(http.StatusConflict, gin.H{"error": "A source with this RSS URL already exists"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Re-enabling a source or pointing it at a new feed gives it a clean slate
+	_, urlChanged := updates["rss_url"]
+	if (req.Active != nil && *req.Active && !source.Active) || urlChanged {
+		if err := services.ResetSourceHealth(app.DB, source.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := app.DB.First(&source, source.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, source)
+}
+
+// loadSource fetches the non-deleted source named by the :id path parameter,
+// writing an error response and returning false if it can't
+func loadSource(c *gin.Context, app *models.App) (models.NewsSource, bool) {
+	var source models.NewsSource
+
+	id, ok := parseIDParam(c)
+	if !ok {
+		return source, false
+	}
+
+	if err := app.DB.First(&source, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Source not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return source, false
+	}
+
+	return source, true
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+)
+
+// AdminMiddleware only lets through users whose email is listed in the
+// comma-separated ADMIN_EMAILS environment variable. Must run after AuthMiddleware.
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			c.Abort()
+			return
+		}
+		currentUser := user.(models.User)
+
+		for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+			email = strings.TrimSpace(email)
+			if email != "" && strings.EqualFold(email, currentUser.Email) {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		c.Abort()
+	}
+}
@@ -8,10 +8,20 @@ import (
 
 type UserAlert struct {
 	ID                  uint           `json:"id" gorm:"primaryKey"`
-	UserID              uint           `json:"user_id" gorm:"not null"`                        // Which user
-	Keywords            pq.StringArray `json:"keywords" gorm:"type:text[]"`                    // Keywords to watch for ["ukraine", "war"]
-	SourceIDs           pq.Int64Array  `json:"source_ids" gorm:"type:integer[]"`               // Which sources to monitor (empty = all)
-	NotificationMethods pq.StringArray `json:"notification_methods" gorm:"type:text[]"`       // ["email", "push", "sms"]
-	Active              bool           `json:"active" gorm:"default:true"`                     // Whether alert is enabled
+	UserID              uint           `json:"user_id" gorm:"not null"`                         // Which user
+	Keywords            pq.StringArray `json:"keywords" gorm:"type:text[]"`                     // Keywords to watch for ["ukraine", "war"]
+	Query               string         `json:"query" gorm:"type:text"`                          // Boolean query, e.g. `tesla AND NOT stock`; overrides Keywords
+	SourceIDs           pq.Int64Array  `json:"source_ids" gorm:"type:integer[]"`                // Which sources to monitor (empty = all)
+	NotificationMethods pq.StringArray `json:"notification_methods" gorm:"type:text[]"`         // ["email", "push", "webhook"]
+	Active              bool           `json:"active" gorm:"default:true"`                      // Whether alert is enabled
+	SnoozedUntil        *time.Time     `json:"snoozed_until"`                                   // Matches are ignored until this time
+	Type                string         `json:"type" gorm:"not null;default:'match'"`            // "match" for every matching article, "spike" for coverage spikes
+	SpikeWindowMinutes  int            `json:"spike_window_minutes"`                            // Spike alerts: length of the window compared to the baseline
+	SpikeBaselineHours  int            `json:"spike_baseline_hours"`                            // Spike alerts: history the baseline rate is computed over
+	SpikeFactor         float64        `json:"spike_factor"`                                    // Spike alerts: how many times the baseline counts as a spike
+	SpikeMinArticles    int            `json:"spike_min_articles"`                              // Spike alerts: fewest articles in a window that can fire
+	DeliveryMode        string         `json:"delivery_mode" gorm:"not null;default:'instant'"` // "instant", "hourly", "daily" or "weekly"
+	DigestTime          string         `json:"digest_time" gorm:"not null;default:'08:00'"`     // Local "HH:MM" daily and weekly digests go out at
+	DigestWeekday       string         `json:"digest_weekday" gorm:"not null;default:'monday'"` // Day weekly digests go out on
 	CreatedAt           time.Time      `json:"created_at"`
 }
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// DigestItem is an alert match waiting to go out in a digest
+type DigestItem struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	AlertID   uint      `json:"alert_id" gorm:"not null;uniqueIndex:idx_digest_item_alert_article"`
+	UserID    uint      `json:"user_id" gorm:"not null"`
+	ArticleID uint      `json:"article_id" gorm:"not null;uniqueIndex:idx_digest_item_alert_article"`
+	DigestID  *uint     `json:"digest_id,omitempty" gorm:"index"` // Set once the match has been included in a digest
+	CreatedAt time.Time `json:"created_at" gorm:"index"`          // When the match was found
+}
+
+// AlertDigest is one batch of matches delivered together
+type AlertDigest struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	AlertID      uint      `json:"alert_id" gorm:"not null;index"`
+	UserID       uint      `json:"user_id" gorm:"not null;index"`
+	Mode         string    `json:"mode"`          // Delivery mode the digest was built for
+	ArticleCount int       `json:"article_count"` // Matches included
+	PeriodStart  time.Time `json:"period_start"`  // Oldest match included
+	PeriodEnd    time.Time `json:"period_end"`    // When the digest was cut
+	CreatedAt    time.Time `json:"created_at"`
+}
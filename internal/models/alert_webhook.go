@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// AlertWebhook is an endpoint a user registered to receive an alert's matches as signed JSON
+type AlertWebhook struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	AlertID     uint      `json:"alert_id" gorm:"not null;index"`
+	UserID      uint      `json:"user_id" gorm:"not null"`
+	URL         string    `json:"url" gorm:"not null"`
+	Description string    `json:"description"`
+	Secret      string    `json:"secret,omitempty" gorm:"not null"` // Only returned when created or rotated
+	Active      bool      `json:"active" gorm:"default:true"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery logs one attempt to POST an event to an AlertWebhook
+type WebhookDelivery struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	WebhookID  uint      `json:"webhook_id" gorm:"not null;index"`
+	EventID    string    `json:"event_id" gorm:"index"` // Same across retries of one event
+	Event      string    `json:"event"`                 // "alert.matched" or "webhook.test"
+	ArticleID  uint      `json:"article_id,omitempty"`
+	StatusCode int       `json:"status_code"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
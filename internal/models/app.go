@@ -1,12 +1,15 @@
 package models
 
 import (
+	"net/http"
 	"sync"
 	"time"
 
 	"firebase.google.com/go/v4/auth"
+	"firebase.google.com/go/v4/messaging"
 	"github.com/gin-gonic/gin"
 	"github.com/mmcdole/gofeed"
+	"github.com/mrrobotisreal/rss_today_api/internal/notify"
 	"github.com/robfig/cron/v3"
 	"gorm.io/gorm"
 )
@@ -15,8 +18,11 @@ type App struct {
 	DB           *gorm.DB
 	Router       *gin.Engine
 	Parser       *gofeed.Parser
+	FeedClient   *http.Client // Used for monitoring fetches so we control caching headers
 	Cron         *cron.Cron
 	FirebaseAuth *auth.Client
+	Messaging    *messaging.Client // FCM, for push notifications
+	Notifiers    *notify.Registry  // Notification channels configured at startup
 	Mu           sync.RWMutex
 	LastRun      time.Time
 }
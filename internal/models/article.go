@@ -12,9 +12,17 @@ type Article struct {
 	Title       string         `json:"title" gorm:"not null"`                       // Article headline
 	Description string         `json:"description"`                                 // Article summary
 	Link        string         `json:"link" gorm:"unique;not null"`                 // Original article URL
-	PubDate     time.Time      `json:"pub_date"`                                    // When published
+	PubDate     time.Time      `json:"pub_date" gorm:"index"`                       // When published
 	ContentHash string         `json:"content_hash" gorm:"unique"`                  // Hash to detect duplicates
 	Keywords    pq.StringArray `json:"keywords" gorm:"type:text[]"`                 // Extracted keywords
+	Content     string         `json:"content,omitempty" gorm:"type:text"`          // Body text extracted from the linked page, if the source has extraction on
+	WordCount   int            `json:"word_count"`                                  // Words in Content
+	ImageURL    string         `json:"image_url,omitempty"`                         // Lead image from the linked page
+	Byline      string         `json:"byline,omitempty"`                            // Author from the linked page
+	SimHash     int64          `json:"-" gorm:"not null;default:0"`                 // Fingerprint of title and description for near-duplicate detection; 0 if too short
+	SimBands    pq.Int64Array  `json:"-" gorm:"type:integer[];index:,type:gin"`    // SimHash split into bands, so candidates can be found by index
+	ClusterID   *uint          `json:"cluster_id,omitempty" gorm:"index"`           // ID of the first article of the same story, when it has near-duplicates
+	DuplicateCount int         `json:"duplicate_count" gorm:"not null;default:0"`   // Near-duplicates found later; only set on a cluster's first article
 	CreatedAt   time.Time      `json:"created_at"`                                  // When we found it
 	Source      NewsSource     `json:"source,omitempty" gorm:"foreignKey:SourceID"` // Join with source
 }
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// DeviceToken is an FCM registration token for one of a user's devices
+type DeviceToken struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	Token     string    `json:"token" gorm:"unique;not null"`
+	Platform  string    `json:"platform"` // "ios", "android", "web"
+	CreatedAt time.Time `json:"created_at"`
+}
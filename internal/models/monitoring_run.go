@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+type MonitoringRun struct {
+	ID               uint                       `json:"id" gorm:"primaryKey"`
+	Trigger          string                     `json:"trigger" gorm:"not null"` // "scheduled", "startup", "manual"
+	Status           string                     `json:"status" gorm:"not null"`  // "running", "completed", "failed", "skipped"
+	Error            string                     `json:"error,omitempty"`         // Why the whole run failed or was skipped
+	InstanceID       string                     `json:"instance_id"`             // Which API process ran it
+	StartedAt        time.Time                  `json:"started_at"`
+	FinishedAt       *time.Time                 `json:"finished_at"`
+	SourcesAttempted int                        `json:"sources_attempted"`
+	SourcesFailed    int                        `json:"sources_failed"`
+	SourcesSkipped   int                        `json:"sources_skipped"` // Locked by another instance or rate limited
+	ArticlesParsed   int                        `json:"articles_parsed"`
+	ArticlesNew      int                        `json:"articles_new"`
+	AlertsMatched    int                        `json:"alerts_matched"`
+	SourceErrors     []MonitoringRunSourceError `json:"source_errors,omitempty" gorm:"foreignKey:RunID"`
+}
+
+// MonitoringRunSourceError records why one source failed during a run
+type MonitoringRunSourceError struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	RunID      uint   `json:"run_id" gorm:"not null;index"`
+	SourceID   uint   `json:"source_id"`
+	SourceName string `json:"source_name"`
+	Error      string `json:"error"`
+	Skipped    bool   `json:"skipped"` // True when the source was skipped rather than failed
+}
@@ -4,9 +4,18 @@ import "time"
 
 type NotificationSent struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
-	UserID    uint      `json:"user_id" gorm:"not null"`    // Which user was notified
-	ArticleID uint      `json:"article_id" gorm:"not null"` // Which article triggered notification
-	AlertID   uint      `json:"alert_id" gorm:"not null"`   // Which alert rule matched
-	Method    string    `json:"method" gorm:"not null"`     // "email", "push", "sms"
-	SentAt    time.Time `json:"sent_at"`                    // When notification was sent
+	UserID    uint      `json:"user_id" gorm:"not null"`                                                    // Which user was notified
+	ArticleID uint      `json:"article_id" gorm:"not null;index:idx_notification_alert_article,priority:2"` // Which article triggered notification (0 for digests)
+	AlertID   uint      `json:"alert_id" gorm:"not null;index:idx_notification_alert_article,priority:1"`   // Which alert rule matched
+	Method    string    `json:"method" gorm:"not null"`                                                     // "email", "push", "webhook", "alert_webhook"
+	WebhookID *uint     `json:"webhook_id,omitempty"`                                                       // Target AlertWebhook when Method is "alert_webhook"
+	DigestID  *uint     `json:"digest_id,omitempty"`                                                        // AlertDigest delivered, instead of a single article
+	SpikeID   *uint     `json:"spike_id,omitempty"`                                                         // SpikeEvent delivered, instead of a single article
+	Status    string    `json:"status"`                                                                     // "pending", "sent", "failed", "skipped"
+	Error     string    `json:"error,omitempty"`                                                            // Why delivery failed or was skipped
+	SentAt    time.Time `json:"sent_at"`                                                                    // When delivery was last attempted
+	CreatedAt time.Time `json:"created_at" gorm:"index"`                                                    // When the notification was queued; never changes
+
+	ReadAt      *time.Time `json:"read_at"`      // When the user read it in their inbox
+	DismissedAt *time.Time `json:"dismissed_at"` // When the user removed it from their inbox
 }
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// NotificationOutbox queues one NotificationSent for delivery. It's written in
+// the same transaction as the notification itself and worked off by the dispatcher.
+type NotificationOutbox struct {
+	ID             uint             `json:"id" gorm:"primaryKey"`
+	NotificationID uint             `json:"notification_id" gorm:"not null;uniqueIndex"`
+	Status         string           `json:"status" gorm:"not null;index"` // "pending", "done", "dead"
+	Attempts       int              `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt  time.Time        `json:"next_attempt_at" gorm:"index"` // Also used as a lease while a dispatcher works on it
+	LastError      string           `json:"last_error,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+	Notification   NotificationSent `json:"notification" gorm:"foreignKey:NotificationID"`
+}
@@ -1,12 +1,89 @@
 package models
 
-import "time"
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type NewsSource struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	Name      string    `json:"name" gorm:"not null"`          // e.g. "BBC News"
-	URL       string    `json:"url"`                           // e.g. "https://bbc.com"
-	RSSURL    string    `json:"rss_url" gorm:"not null"`       // e.g. "http://feeds.bbci.co.uk/news/rss.xml"
-	Active    bool      `json:"active" gorm:"default:true"`    // Whether to monitor this source
-	CreatedAt time.Time `json:"created_at"`
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Name     string `json:"name" gorm:"not null"`       // e.g. "BBC News"
+	URL      string `json:"url"`                        // e.g. "https://bbc.com"
+	RSSURL   string `json:"rss_url" gorm:"not null"`    // e.g. "http://feeds.bbci.co.uk/news/rss.xml"
+	Category string `json:"category"`                   // e.g. "World/Europe", nested OPML folders joined with "/"
+	Active   bool   `json:"active" gorm:"default:true"` // Whether to monitor this source
+
+	// NormalizeFeedURL(RSSURL), unique among live sources so two can't share a feed
+	RSSURLKey *string `json:"-" gorm:"uniqueIndex:idx_news_sources_rss_url_key,where:deleted_at IS NULL"`
+
+	// Polling schedule
+	PollIntervalMinutes     int  `json:"poll_interval_minutes"`                               // 0 = DEFAULT_POLL_INTERVAL
+	AdaptivePolling         bool `json:"adaptive_polling"`                                    // Learn the interval from publish cadence and feed hints
+	AdaptiveIntervalMinutes int  `json:"adaptive_interval_minutes" gorm:"not null;default:0"` // Last learned interval
+	UpdateHintMinutes       int  `json:"update_hint_minutes" gorm:"not null;default:0"`       // From the feed's <ttl> or sy:updatePeriod
+
+	// Download each new article's page and extract its full text, for feeds that only carry a teaser
+	ExtractContent bool `json:"extract_content"`
+
+	// HTTP caching state for conditional GETs
+	ETag             string `json:"etag"`                                         // ETag from the last 200 response
+	LastModified     string `json:"last_modified"`                                // Last-Modified from the last 200 response
+	FeedHash         string `json:"-"`                                            // SHA-256 of the last body, for servers that ignore validators
+	LastFetchBytes   int64  `json:"last_fetch_bytes" gorm:"not null;default:0"`   // Size of the last full download
+	BytesFetched     int64  `json:"bytes_fetched" gorm:"not null;default:0"`      // Total bytes downloaded
+	BytesSaved       int64  `json:"bytes_saved" gorm:"not null;default:0"`        // Estimated bytes avoided thanks to 304 responses
+	NotModifiedCount int64  `json:"not_modified_count" gorm:"not null;default:0"` // Number of 304 responses
+
+	// Fetch health, maintained by the monitor
+	ConsecutiveFailures int        `json:"consecutive_failures" gorm:"not null;default:0"`
+	LastSuccessAt       *time.Time `json:"last_success_at"`
+	LastErrorAt         *time.Time `json:"last_error_at"`
+	LastError           string     `json:"last_error"`
+	LastStatusCode      int        `json:"last_status_code"`
+	NextFetchAt         *time.Time `json:"next_fetch_at"`    // When the scheduler should poll next (nil = now)
+	AutoDisabledAt      *time.Time `json:"auto_disabled_at"` // Set when deactivated for failing too often
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"` // Soft delete - articles keep pointing at the row
+}
+
+// BeforeCreate sets RSSURLKey for every new source. Updates changing RSSURL
+// set rss_url_key themselves.
+func (s *NewsSource) BeforeCreate(tx *gorm.DB) error {
+	key := NormalizeFeedURL(s.RSSURL)
+	s.RSSURLKey = &key
+	return nil
+}
+
+// NormalizeFeedURL reduces a feed URL to a key that's the same for spellings
+// of the same address: scheme and host lowercased, default ports, fragments
+// and trailing slashes dropped
+func NormalizeFeedURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return strings.ToLower(rawURL)
+	}
+
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	host, port := strings.ToLower(parsed.Hostname()), parsed.Port()
+	if (parsed.Scheme == "http" && port == "80") || (parsed.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6 literal
+	}
+	parsed.Host = host
+	if port != "" {
+		parsed.Host += ":" + port
+	}
+	parsed.Path = strings.TrimRight(parsed.Path, "/")
+	parsed.RawPath = ""
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+	return parsed.String()
 }
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// SpikeEvent records a spike alert firing for one of its keywords or sources
+type SpikeEvent struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	AlertID     uint      `json:"alert_id" gorm:"not null;index:idx_spike_event_alert_series"`
+	UserID      uint      `json:"user_id" gorm:"not null"`
+	Series      string    `json:"series" gorm:"not null;index:idx_spike_event_alert_series"` // Keyword, or "source:<id>"
+	Label       string    `json:"label"`                                                     // Keyword or source name, for display
+	WindowStart time.Time `json:"window_start"`
+	WindowEnd   time.Time `json:"window_end"`
+	Count       int       `json:"count"`    // Articles in the window
+	Baseline    float64   `json:"baseline"` // Expected articles per window
+	Ratio       float64   `json:"ratio"`    // Count / baseline
+	CreatedAt   time.Time `json:"created_at"`
+}
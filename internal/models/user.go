@@ -7,6 +7,7 @@ type User struct {
 	FirebaseUID  string    `json:"firebase_uid" gorm:"unique;not null"`
 	Email        string    `json:"email" gorm:"not null"`
 	DisplayName  string    `json:"display_name"`
+	Timezone     string    `json:"timezone" gorm:"not null;default:'UTC'"` // IANA zone digests are scheduled in, e.g. "Europe/Kyiv"
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
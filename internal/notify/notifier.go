@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Delivery outcomes recorded on each NotificationSent
+const (
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// ErrSkipped marks a delivery that was deliberately not attempted,
+// e.g. the user has no device registered for push
+var ErrSkipped = errors.New("skipped")
+
+// Recipient is who a notification goes to, with an address for each channel
+type Recipient struct {
+	UserID       uint
+	Email        string
+	DeviceTokens []string // FCM registration tokens
+}
+
+// Message is a channel-agnostic notification
+type Message struct {
+	Subject string            // Email subject / push title
+	Text    string            // Plain-text body
+	HTML    string            // Optional HTML body for email
+	URL     string            // Link the notification points to
+	Data    map[string]string // Structured fields for webhooks and push payloads
+}
+
+// Notifier delivers messages over one channel
+type Notifier interface {
+	// Channel is the name used in UserAlert.NotificationMethods, e.g. "email"
+	Channel() string
+	Send(ctx context.Context, to Recipient, msg Message) error
+}
+
+// Registry holds the notifiers configured at startup
+type Registry struct {
+	notifiers map[string]Notifier
+}
+
+func NewRegistry() *Registry {
+	return &Registry{notifiers: map[string]Notifier{}}
+}
+
+// Register adds n, replacing any notifier already registered for its channel
+func (r *Registry) Register(n Notifier) {
+	r.notifiers[n.Channel()] = n
+}
+
+// Channels lists the configured channel names
+func (r *Registry) Channels() []string {
+	channels := make([]string, 0, len(r.notifiers))
+	for channel := range r.notifiers {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// Deliver sends msg over channel and reports the outcome as one of the Status constants
+func (r *Registry) Deliver(ctx context.Context, channel string, to Recipient, msg Message) (string, error) {
+	if r == nil {
+		return StatusSkipped, fmt.Errorf("no notification channels configured")
+	}
+
+	notifier, ok := r.notifiers[channel]
+	if !ok {
+		return StatusSkipped, fmt.Errorf("channel %q is not configured", channel)
+	}
+
+	if err := notifier.Send(ctx, to, msg); err != nil {
+		if errors.Is(err, ErrSkipped) {
+			return StatusSkipped, err
+		}
+		return StatusFailed, err
+	}
+	return StatusSent, nil
+}
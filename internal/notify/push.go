@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"firebase.google.com/go/v4/messaging"
+)
+
+// PushNotifier sends notifications to the user's devices through Firebase Cloud Messaging
+type PushNotifier struct {
+	Client *messaging.Client
+
+	// OnInvalidTokens, if set, is called with device tokens FCM says are no
+	// longer registered so they can be forgotten
+	OnInvalidTokens func(tokens []string)
+}
+
+func (n *PushNotifier) Channel() string { return "push" }
+
+func (n *PushNotifier) Send(ctx context.Context, to Recipient, msg Message) error {
+	if len(to.DeviceTokens) == 0 {
+		return fmt.Errorf("%w: user has no registered devices", ErrSkipped)
+	}
+
+	data := map[string]string{}
+	for key, value := range msg.Data {
+		data[key] = value
+	}
+	if msg.URL != "" {
+		data["url"] = msg.URL
+	}
+
+	response, err := n.Client.SendEachForMulticast(ctx, &messaging.MulticastMessage{
+		Tokens: to.DeviceTokens,
+		Notification: &messaging.Notification{
+			Title: msg.Subject,
+			Body:  msg.Text,
+		},
+		Data: data,
+	})
+	if err != nil {
+		return err
+	}
+
+	var invalid []string
+	var lastErr error
+	for i, result := range response.Responses {
+		if result.Success {
+			continue
+		}
+		lastErr = result.Error
+		if messaging.IsRegistrationTokenNotRegistered(result.Error) {
+			invalid = append(invalid, to.DeviceTokens[i])
+		}
+	}
+
+	if len(invalid) > 0 && n.OnInvalidTokens != nil {
+		n.OnInvalidTokens(invalid)
+	}
+
+	if response.SuccessCount == 0 {
+		return fmt.Errorf("push failed for all %d devices: %v", len(to.DeviceTokens), lastErr)
+	}
+	return nil
+}
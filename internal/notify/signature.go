@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Signature headers sent with every user webhook
+const (
+	TimestampHeader = "X-RSSToday-Timestamp"
+	SignatureHeader = "X-RSSToday-Signature"
+	EventHeader     = "X-RSSToday-Event"
+)
+
+// Sign computes the hex HMAC-SHA256 of "<timestamp>.<body>" with secret.
+// Receivers recompute it and compare, rejecting stale timestamps to stop replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignedResponse is what a signed POST got back. The body isn't kept: the
+// caller chose the URL, so echoing what it returned would let users read
+// pages they shouldn't be able to reach.
+type SignedResponse struct {
+	StatusCode int
+	Duration   time.Duration
+}
+
+// PostSigned POSTs body to url with timestamp and signature headers
+func PostSigned(ctx context.Context, client *http.Client, url, secret, event string, body []byte) (SignedResponse, error) {
+	var result SignedResponse
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return result, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "RSSToday-Webhooks/1.0")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, "t="+strconv.FormatInt(timestamp, 10)+",v1="+Sign(secret, timestamp, body))
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	result.Duration = time.Since(start)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+
+	// Drain a little so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	result.StatusCode = resp.StatusCode
+	return result, nil
+}
@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WebhookNotifier POSTs every notification as JSON to one configured URL,
+// e.g. a Slack/Teams relay or an internal service
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// webhookPayload is the JSON body sent to the webhook
+type webhookPayload struct {
+	UserID  uint              `json:"user_id"`
+	Subject string            `json:"subject"`
+	Text    string            `json:"text"`
+	URL     string            `json:"url,omitempty"`
+	Data    map[string]string `json:"data,omitempty"`
+	SentAt  time.Time         `json:"sent_at"`
+}
+
+func (n *WebhookNotifier) Channel() string { return "webhook" }
+
+func (n *WebhookNotifier) Send(ctx context.Context, to Recipient, msg Message) error {
+	payload, err := json.Marshal(webhookPayload{
+		UserID:  to.UserID,
+		Subject: msg.Subject,
+		Text:    msg.Text,
+		URL:     msg.URL,
+		Data:    msg.Data,
+		SentAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
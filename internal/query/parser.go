@@ -0,0 +1,330 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	// MaxLength is the longest query accepted, in characters
+	MaxLength = 1000
+	// maxDepth bounds parenthesis and NOT nesting
+	maxDepth = 32
+)
+
+// SyntaxError describes an invalid query. Pos is the 1-based character
+// position the problem was found at.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokPhrase
+	tokField
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	pos   int // 1-based
+	minus bool
+}
+
+// describe names a token for error messages
+func (t token) describe() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokPhrase:
+		return `"` + t.text + `"`
+	case tokField:
+		return `"` + t.text + `:"`
+	case tokAnd, tokOr, tokNot:
+		return strings.ToUpper(t.text)
+	default:
+		return `"` + t.text + `"`
+	}
+}
+
+// Parse validates text and compiles it into a Query
+func Parse(text string) (*Query, error) {
+	if len([]rune(text)) > MaxLength {
+		return nil, &SyntaxError{Pos: MaxLength + 1, Msg: fmt.Sprintf("query is longer than %d characters", MaxLength)}
+	}
+
+	tokens, err := lex(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 1 {
+		return nil, &SyntaxError{Pos: 1, Msg: "query is empty"}
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr("", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokEOF {
+		if tok.kind == tokRParen {
+			return nil, &SyntaxError{Pos: tok.pos, Msg: `unexpected ")" without a matching "("`}
+		}
+		return nil, &SyntaxError{Pos: tok.pos, Msg: "unexpected " + tok.describe()}
+	}
+
+	return &Query{root: root}, nil
+}
+
+// lex splits text into tokens, always ending with tokEOF
+func lex(text string) ([]token, error) {
+	runes := []rune(text)
+	var tokens []token
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i + 1})
+			i++
+
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i + 1})
+			i++
+
+		case r == '"' || (r == '-' && i+1 < len(runes) && runes[i+1] == '"'):
+			start := i
+			minus := r == '-'
+			if minus {
+				i++
+			}
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, &SyntaxError{Pos: i + 1, Msg: "unterminated quoted phrase"}
+			}
+			tokens = append(tokens, token{kind: tokPhrase, text: string(runes[i+1 : end]), pos: start + 1, minus: minus})
+			i = end + 1
+
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`()":`, runes[i]) {
+				i++
+			}
+
+			word := string(runes[start:i])
+			if i < len(runes) && runes[i] == ':' {
+				if word == "" {
+					return nil, &SyntaxError{Pos: start + 1, Msg: `unexpected ":" without a field name before it`}
+				}
+				if _, ok := fieldAliases[strings.ToLower(word)]; ok {
+					i++
+					tokens = append(tokens, token{kind: tokField, text: word, pos: start + 1})
+					continue
+				}
+				// Not a field, so the colon is part of the term, as in
+				// https://example.com or 9:30
+				for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`()"`, runes[i]) {
+					i++
+				}
+				word = string(runes[start:i])
+			}
+
+			tok := token{kind: tokWord, text: word, pos: start + 1}
+			switch strings.ToUpper(word) {
+			case "AND", "&&":
+				tok.kind = tokAnd
+			case "OR", "||":
+				tok.kind = tokOr
+			case "NOT":
+				tok.kind = tokNot
+			default:
+				if strings.HasPrefix(word, "-") && len(word) > 1 {
+					tok.text = word[1:]
+					tok.minus = true
+				}
+			}
+			tokens = append(tokens, tok)
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, pos: len(runes) + 1}), nil
+}
+
+type parser struct {
+	tokens []token
+	next   int
+}
+
+func (p *parser) peek() token { return p.tokens[p.next] }
+
+func (p *parser) advance() token {
+	tok := p.tokens[p.next]
+	if tok.kind != tokEOF {
+		p.next++
+	}
+	return tok
+}
+
+// parseOr: and ("OR" and)*
+func (p *parser) parseOr(field string, depth int) (node, error) {
+	left, err := p.parseAnd(field, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokOr {
+		op := p.advance()
+		right, err := p.parseAnd(field, depth)
+		if err != nil {
+			return nil, afterOperator(err, op)
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+// parseAnd: unary (["AND"] unary)*, where adjacent terms are implicitly ANDed
+func (p *parser) parseAnd(field string, depth int) (node, error) {
+	left, err := p.parseUnary(field, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch p.peek().kind {
+		case tokAnd:
+			op := p.advance()
+			right, err := p.parseUnary(field, depth)
+			if err != nil {
+				return nil, afterOperator(err, op)
+			}
+			left = andNode{left, right}
+		case tokWord, tokPhrase, tokField, tokNot, tokLParen:
+			right, err := p.parseUnary(field, depth)
+			if err != nil {
+				return nil, err
+			}
+			left = andNode{left, right}
+		default:
+			return left, nil
+		}
+	}
+}
+
+// parseUnary: "NOT" unary | primary
+func (p *parser) parseUnary(field string, depth int) (node, error) {
+	if depth > maxDepth {
+		return nil, &SyntaxError{Pos: p.peek().pos, Msg: fmt.Sprintf("query is nested more than %d levels deep", maxDepth)}
+	}
+
+	if p.peek().kind == tokNot {
+		op := p.advance()
+		child, err := p.parseUnary(field, depth+1)
+		if err != nil {
+			return nil, afterOperator(err, op)
+		}
+		return notNode{child}, nil
+	}
+	return p.parsePrimary(field, depth)
+}
+
+// parsePrimary: "(" or ")" | field ":" (term | "(" or ")") | term
+func (p *parser) parsePrimary(field string, depth int) (node, error) {
+	tok := p.advance()
+
+	switch tok.kind {
+	case tokLParen:
+		inner, err := p.parseOr(field, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.advance(); closing.kind != tokRParen {
+			if closing.kind == tokEOF {
+				return nil, &SyntaxError{Pos: tok.pos, Msg: `missing ")" to close this "("`}
+			}
+			return nil, &SyntaxError{Pos: closing.pos, Msg: `expected ")" but found ` + closing.describe()}
+		}
+		return inner, nil
+
+	case tokField:
+		if field != "" {
+			return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("field %q can't be used inside %s:", tok.text, field)}
+		}
+		// The lexer only makes field tokens of known fields
+		scoped := fieldAliases[strings.ToLower(tok.text)]
+		switch p.peek().kind {
+		case tokWord, tokPhrase, tokLParen:
+			return p.parsePrimary(scoped, depth)
+		default:
+			next := p.peek()
+			return nil, &SyntaxError{Pos: next.pos, Msg: fmt.Sprintf("expected a term after %s: but found %s", tok.text, next.describe())}
+		}
+
+	case tokWord, tokPhrase:
+		term, err := buildTerm(tok, field)
+		if err != nil {
+			return nil, err
+		}
+		if tok.minus {
+			return notNode{term}, nil
+		}
+		return term, nil
+
+	case tokRParen:
+		return nil, &SyntaxError{Pos: tok.pos, Msg: `unexpected ")"`}
+
+	case tokEOF:
+		return nil, &SyntaxError{Pos: tok.pos, Msg: "expected a term but reached the end of the query"}
+
+	default:
+		return nil, &SyntaxError{Pos: tok.pos, Msg: "expected a term but found " + tok.describe()}
+	}
+}
+
+// buildTerm turns a word or phrase token into a termNode
+func buildTerm(tok token, field string) (node, error) {
+	if tok.kind == tokPhrase && strings.TrimSpace(tok.text) == "" {
+		return nil, &SyntaxError{Pos: tok.pos, Msg: "empty quoted phrase"}
+	}
+
+	words := tokenizePattern(tok.text)
+	if len(words) == 0 {
+		return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("term %q has no letters or digits to match", tok.text)}
+	}
+	for _, word := range words {
+		if strings.Trim(word, "*?") == "" {
+			return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("wildcard term %q must include at least one letter or digit", tok.text)}
+		}
+	}
+
+	return termNode{field: field, words: words, phrase: tok.kind == tokPhrase}, nil
+}
+
+// afterOperator rewords "reached the end" errors to name the operator missing its operand
+func afterOperator(err error, op token) error {
+	if syntaxErr, ok := err.(*SyntaxError); ok && syntaxErr.Msg == "expected a term but reached the end of the query" {
+		return &SyntaxError{Pos: syntaxErr.Pos, Msg: "expected a term after " + op.describe() + " but reached the end of the query"}
+	}
+	return err
+}
@@ -0,0 +1,101 @@
+package query
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string // Canonical form
+	}{
+		{"tesla", "tesla"},
+		{"Tesla", "tesla"},
+		{"tesla stock", "(tesla AND stock)"},
+		{"tesla AND stock", "(tesla AND stock)"},
+		{"tesla and stock", "(tesla AND stock)"},
+		{"tesla && stock", "(tesla AND stock)"},
+		{"tesla OR rivian", "(tesla OR rivian)"},
+		{"tesla || rivian", "(tesla OR rivian)"},
+		{"NOT stock", "NOT stock"},
+		{"-stock", "NOT stock"},
+		{`-"stock price"`, `NOT "stock price"`},
+		{"tesla AND NOT stock", "(tesla AND NOT stock)"},
+		{"a OR b AND NOT c", "(a OR (b AND NOT c))"},
+		{"(tesla OR rivian) AND recall", "((tesla OR rivian) AND recall)"},
+		{`"climate change"`, `"climate change"`},
+		{`"and"`, `"and"`},
+		{"flood*", "flood*"},
+		{"colo?r", "colo?r"},
+		{"title:tesla", "title:tesla"},
+		{"TITLE:tesla", "title:tesla"},
+		{"desc:tesla", "description:tesla"},
+		{"body:tesla", "content:tesla"},
+		{`title:"climate change"`, `title:"climate change"`},
+		{"title:(tesla OR rivian)", "(title:tesla OR title:rivian)"},
+		{"source:bbc tesla", "(source:bbc AND tesla)"},
+		// Words that aren't fields keep their colon as part of the term
+		{"https://example.com/news", `"https example com news"`},
+		{"9:30", `"9 30"`},
+		{"foo:bar", `"foo bar"`},
+		{"title:foo:bar", `title:"foo bar"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tt.query, err)
+			}
+			if got := q.String(); got != tt.want {
+				t.Errorf("Parse(%q) = %s, want %s", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+		msg   string // Substring of the message
+	}{
+		{"", 1, "query is empty"},
+		{"   ", 1, "query is empty"},
+		{"tesla AND", 10, "expected a term after AND but reached the end of the query"},
+		{"tesla OR", 9, "expected a term after OR but reached the end of the query"},
+		{"NOT", 4, "expected a term after NOT but reached the end of the query"},
+		{"AND tesla", 1, "expected a term but found AND"},
+		{"(tesla", 1, `missing ")" to close this "("`},
+		{"tesla)", 6, `unexpected ")" without a matching "("`},
+		{"()", 2, `unexpected ")"`},
+		{`"climate change`, 1, "unterminated quoted phrase"},
+		{`""`, 1, "empty quoted phrase"},
+		{":tesla", 1, `unexpected ":" without a field name before it`},
+		{"title:", 7, "expected a term after title: but found end of query"},
+		{"title:source:bbc", 7, `expected a term after title: but found "source:"`},
+		{"title:(source:bbc)", 8, `field "source" can't be used inside title:`},
+		{"***", 1, "must include at least one letter or digit"},
+		{"!!", 1, "has no letters or digits to match"},
+		{strings.Repeat("a", MaxLength+1), MaxLength + 1, "query is longer than"},
+		{strings.Repeat("(", maxDepth+2) + "a" + strings.Repeat(")", maxDepth+2), maxDepth + 2, "nested more than"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := Parse(tt.query)
+			if err == nil {
+				t.Fatalf("Parse(%q) succeeded, want error", tt.query)
+			}
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Parse(%q) returned %T, want *SyntaxError", tt.query, err)
+			}
+			if syntaxErr.Pos != tt.pos || !strings.Contains(syntaxErr.Msg, tt.msg) {
+				t.Errorf("Parse(%q) = %q at %d, want %q at %d", tt.query, syntaxErr.Msg, syntaxErr.Pos, tt.msg, tt.pos)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+// Package query implements the boolean search language used by alerts, e.g.
+//
+//	tesla AND NOT stock
+//	title:"climate change" OR (flood* source:bbc)
+//
+// Terms match whole words case-insensitively. Adjacent terms are ANDed.
+package query
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Fields a term can be scoped to with "field:term"
+const (
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldKeywords    = "keywords"
+	FieldContent     = "content"
+	FieldSource      = "source"
+)
+
+// defaultFields are searched by terms without a field prefix
+var defaultFields = []string{FieldTitle, FieldDescription, FieldKeywords, FieldContent}
+
+// fieldAliases maps what users may type before ":" to a field
+var fieldAliases = map[string]string{
+	"title":       FieldTitle,
+	"description": FieldDescription,
+	"desc":        FieldDescription,
+	"keywords":    FieldKeywords,
+	"keyword":     FieldKeywords,
+	"content":     FieldContent,
+	"body":        FieldContent,
+	"source":      FieldSource,
+}
+
+// Document is the text a query is matched against
+type Document struct {
+	Title       string
+	Description string
+	Keywords    []string
+	Content     string // Full body text, when it was extracted
+	Source      string // Source name
+}
+
+// Query is a parsed, validated query
+type Query struct {
+	root node
+}
+
+// AnySubstring builds a query matching documents that contain any of texts,
+// ignoring case and punctuation, including inside longer words ("ukrain"
+// matches "Ukraine"). Texts are literal, so "*" and "?" in them are ignored.
+// Unlike Parse there is no length limit, so long keyword lists still work.
+// It returns nil when none of texts has any letters or digits.
+func AnySubstring(texts []string) *Query {
+	var root node
+	for _, text := range texts {
+		words := tokenize(text)
+		if len(words) == 0 {
+			continue
+		}
+		// Wildcards at both ends of the phrase let its first and last words
+		// match inside longer words, which is substring matching on words
+		words[0] = "*" + words[0]
+		words[len(words)-1] += "*"
+
+		term := termNode{words: words, phrase: true}
+		if root == nil {
+			root = term
+		} else {
+			root = orNode{root, term}
+		}
+	}
+	if root == nil {
+		return nil
+	}
+	return &Query{root: root}
+}
+
+// Match reports whether doc satisfies the query
+func (q *Query) Match(doc Document) bool {
+	return q.root.match(newIndex(doc))
+}
+
+// String renders the query in canonical form with explicit operators
+func (q *Query) String() string {
+	return q.root.String()
+}
+
+// index holds a document's words per field. Each field is a list of segments,
+// so a phrase can't match across two separate keywords.
+type index map[string][][]string
+
+func newIndex(doc Document) index {
+	idx := index{
+		FieldTitle:       {tokenize(doc.Title)},
+		FieldDescription: {tokenize(doc.Description)},
+		FieldContent:     {tokenize(doc.Content)},
+		FieldSource:      {tokenize(doc.Source)},
+	}
+	for _, keyword := range doc.Keywords {
+		idx[FieldKeywords] = append(idx[FieldKeywords], tokenize(keyword))
+	}
+	return idx
+}
+
+// tokenize lowercases text and splits it into words of letters and digits
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// tokenizePattern is tokenize for query terms, keeping wildcards
+func tokenizePattern(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '*' && r != '?'
+	})
+}
+
+type node interface {
+	match(idx index) bool
+	String() string
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) match(idx index) bool { return n.left.match(idx) && n.right.match(idx) }
+func (n andNode) String() string       { return "(" + n.left.String() + " AND " + n.right.String() + ")" }
+
+type orNode struct{ left, right node }
+
+func (n orNode) match(idx index) bool { return n.left.match(idx) || n.right.match(idx) }
+func (n orNode) String() string       { return "(" + n.left.String() + " OR " + n.right.String() + ")" }
+
+type notNode struct{ child node }
+
+func (n notNode) match(idx index) bool { return !n.child.match(idx) }
+func (n notNode) String() string       { return "NOT " + n.child.String() }
+
+// termNode matches a word, or a phrase of consecutive words, in its fields
+type termNode struct {
+	field  string // Empty searches defaultFields
+	words  []string
+	phrase bool // Written in quotes
+}
+
+func (n termNode) match(idx index) bool {
+	fields := defaultFields
+	if n.field != "" {
+		fields = []string{n.field}
+	}
+
+	for _, field := range fields {
+		for _, segment := range idx[field] {
+			if containsSequence(segment, n.words) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (n termNode) String() string {
+	text := strings.Join(n.words, " ")
+	if n.phrase || len(n.words) > 1 {
+		text = `"` + text + `"`
+	}
+	if n.field != "" {
+		text = n.field + ":" + text
+	}
+	return text
+}
+
+// containsSequence reports whether patterns match consecutive words of segment
+func containsSequence(segment, patterns []string) bool {
+	for start := 0; start+len(patterns) <= len(segment); start++ {
+		matched := true
+		for i, pattern := range patterns {
+			if !wildcardMatch(pattern, segment[start+i]) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardMatch matches word against pattern, where "*" is any run of
+// characters and "?" is exactly one
+func wildcardMatch(pattern, word string) bool {
+	if !strings.ContainsAny(pattern, "*?") {
+		return pattern == word
+	}
+
+	p, w := []rune(pattern), []rune(word)
+	pi, wi := 0, 0
+	star, mark := -1, 0
+	for wi < len(w) {
+		switch {
+		case pi < len(p) && (p[pi] == '?' || p[pi] == w[wi]):
+			pi++
+			wi++
+		case pi < len(p) && p[pi] == '*':
+			star, mark = pi, wi
+			pi++
+		case star >= 0:
+			pi = star + 1
+			mark++
+			wi = mark
+		default:
+			return false
+		}
+	}
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+	return pi == len(p)
+}
@@ -0,0 +1,92 @@
+package query
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	doc := Document{
+		Title:       "Tesla recalls 2,000 Model Y cars over faulty seat belts",
+		Description: "The electric carmaker said the recall affects vehicles built in Berlin.",
+		Keywords:    []string{"tesla", "recall", "electric vehicles"},
+		Content:     "Shares of the company fell 3% after the announcement at 9:30 on https://example.com/news.",
+		Source:      "BBC News",
+	}
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"tesla", true},
+		{"TESLA", true},
+		{"tesl", false}, // Whole words only
+		{"apple", false},
+		{"recalls", true},
+		{"tesla recall", true},
+		{"tesla AND stock", false},
+		{"tesla AND NOT stock", true},
+		{"tesla -recall", false},
+		{"rivian OR berlin", true},
+		{"rivian OR ford", false},
+		{"(rivian OR tesla) AND belts", true},
+		{`"seat belts"`, true},
+		{`"belts seat"`, false},
+		{`"electric vehicles"`, true},
+		{`"recall electric"`, false}, // Phrases don't span two keywords
+		{"recall*", true},
+		{"*maker", true},
+		{"b?lts", true},
+		{"b?ts", false},
+		{"title:tesla", true},
+		{"title:berlin", false},
+		{"description:berlin", true},
+		{"keywords:recall", true},
+		{"keywords:berlin", false},
+		{"content:shares", true},
+		{"shares", true}, // Content is searched by default
+		{"source:bbc", true},
+		{"bbc", false}, // The source name is only searched with source:
+		{"source:cnn", false},
+		{"title:(rivian OR model)", true},
+		{"2,000", true},
+		{"https://example.com/news", true},
+		{"https://example.com/sport", false},
+		{"9:30", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tt.query, err)
+			}
+			if got := q.Match(doc); got != tt.want {
+				t.Errorf("%q matched = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWildcardMatch(t *testing.T) {
+	tests := []struct {
+		pattern, word string
+		want          bool
+	}{
+		{"flood", "flood", true},
+		{"flood", "floods", false},
+		{"flood*", "flood", true},
+		{"flood*", "flooding", true},
+		{"*coin", "bitcoin", true},
+		{"*coin", "coins", false},
+		{"*rain*", "ukraine", true},
+		{"colo?r", "colour", true},
+		{"colo?r", "color", false},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyy", false},
+		{"caf?", "café", true},
+	}
+
+	for _, tt := range tests {
+		if got := wildcardMatch(tt.pattern, tt.word); got != tt.want {
+			t.Errorf("wildcardMatch(%q, %q) = %v, want %v", tt.pattern, tt.word, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+)
+
+// Advisory lock namespaces (the first key of pg_try_advisory_lock(int, int))
+const (
+	LockNamespaceCycle   int32 = 7301 // Whole monitoring cycle; second key is always 0
+	LockNamespaceSource  int32 = 7302 // One source; second key is the source ID
+	LockNamespaceAlert   int32 = 7303 // Transaction-scoped lock on one alert's matches; second key is the alert ID
+	LockNamespaceCluster int32 = 7304 // Transaction-scoped lock on near-duplicate cluster assignment; second key is always 0
+	LockNamespaceMigrate int32 = 7305 // One-off schema migrations (cmd/migrate); second key is always 0
+)
+
+// InstanceID identifies this API process in lock status and logs
+var InstanceID = func() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}()
+
+// AdvisoryLock is a Postgres session-level advisory lock. It pins one pooled
+// connection for as long as it's held, since the lock belongs to that session.
+type AdvisoryLock struct {
+	conn       *sql.Conn
+	Namespace  int32
+	Key        int32
+	AcquiredAt time.Time
+}
+
+// heldLocks tracks the locks this instance holds, for the admin endpoint
+var heldLocks = struct {
+	sync.Mutex
+	locks map[[2]int32]*AdvisoryLock
+}{locks: map[[2]int32]*AdvisoryLock{}}
+
+// TryAdvisoryLock attempts to take the (namespace, key) lock without waiting.
+// It returns nil, nil when another session already holds it.
+func TryAdvisoryLock(app *models.App, namespace, key int32) (*AdvisoryLock, error) {
+	sqlDB, err := app.DB.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting connection for lock: %v", err)
+	}
+
+	// Tag the session so other instances can see who holds the lock in pg_stat_activity
+	if _, err := conn.ExecContext(ctx, "SELECT set_config('application_name', $1, false)", "rss_today:"+InstanceID); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1, $2)", namespace, key).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error taking advisory lock: %v", err)
+	}
+	if !acquired {
+		conn.Close()
+		return nil, nil
+	}
+
+	lock := &AdvisoryLock{conn: conn, Namespace: namespace, Key: key, AcquiredAt: time.Now()}
+
+	heldLocks.Lock()
+	heldLocks.locks[[2]int32{namespace, key}] = lock
+	heldLocks.Unlock()
+
+	return lock, nil
+}
+
+// Unlock releases the lock and returns its connection to the pool
+func (l *AdvisoryLock) Unlock() {
+	heldLocks.Lock()
+	delete(heldLocks.locks, [2]int32{l.Namespace, l.Key})
+	heldLocks.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1, $2)", l.Namespace, l.Key); err != nil {
+		// Closing the connection below ends the session, which drops the lock anyway
+		log.Printf("Error releasing advisory lock (%d, %d): %v", l.Namespace, l.Key, err)
+		l.conn.Raw(func(driverConn interface{}) error { return driver.ErrBadConn })
+	}
+	l.conn.Close()
+}
+
+// LockInfo describes one advisory lock held by any instance
+type LockInfo struct {
+	Namespace   int32      `json:"namespace"`
+	Key         int32      `json:"key"`
+	Kind        string     `json:"kind"` // "cycle" or "source"
+	SourceID    uint       `json:"source_id,omitempty"`
+	Holder      string     `json:"holder"` // application_name of the holding session
+	PID         int        `json:"pid"`
+	ClientAddr  string     `json:"client_addr,omitempty"`
+	HeldSince   *time.Time `json:"held_since,omitempty"` // Only known for our own locks
+	ThisProcess bool       `json:"this_process"`
+}
+
+// ListAdvisoryLocks reports the monitoring locks currently held across all instances
+func ListAdvisoryLocks(app *models.App) ([]LockInfo, error) {
+	rows, err := app.DB.Raw(`
+		SELECT l.classid::bigint, l.objid::bigint, l.pid, COALESCE(a.application_name, ''), COALESCE(host(a.client_addr), '')
+		FROM pg_locks l
+		LEFT JOIN pg_stat_activity a ON a.pid = l.pid
+		WHERE l.locktype = 'advisory' AND l.objsubid = 2 AND l.granted AND l.classid::bigint IN (?, ?)`,
+		LockNamespaceCycle, LockNamespaceSource).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	heldLocks.Lock()
+	defer heldLocks.Unlock()
+
+	locks := []LockInfo{}
+	for rows.Next() {
+		var info LockInfo
+		if err := rows.Scan(&info.Namespace, &info.Key, &info.PID, &info.Holder, &info.ClientAddr); err != nil {
+			return nil, err
+		}
+
+		switch info.Namespace {
+		case LockNamespaceCycle:
+			info.Kind = "cycle"
+		case LockNamespaceSource:
+			info.Kind = "source"
+			info.SourceID = uint(info.Key)
+		}
+
+		if own, ok := heldLocks.locks[[2]int32{info.Namespace, info.Key}]; ok && info.Holder == "rss_today:"+InstanceID {
+			acquiredAt := own.AcquiredAt
+			info.HeldSince = &acquiredAt
+			info.ThisProcess = true
+		}
+
+		locks = append(locks, info)
+	}
+
+	sort.Slice(locks, func(i, j int) bool {
+		if locks[i].Namespace != locks[j].Namespace {
+			return locks[i].Namespace < locks[j].Namespace
+		}
+		return locks[i].Key < locks[j].Key
+	})
+
+	return locks, rows.Err()
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+)
+
+// BackfillMaxDays is the furthest back a new alert can be backfilled
+const BackfillMaxDays = 30
+
+// Ways backfilled matches can be delivered
+const (
+	BackfillDigest = "digest" // One digest with every match, sent right away
+	BackfillEach   = "each"   // A notification per match, as if they had just arrived
+)
+
+// BackfillResult reports what a backfill found
+type BackfillResult struct {
+	Days            int    `json:"days"`
+	Delivery        string `json:"delivery"`
+	ArticlesScanned int    `json:"articles_scanned"`
+	Matches         int    `json:"matches"`
+	DigestID        *uint  `json:"digest_id,omitempty"`
+}
+
+// BackfillAlert matches a newly created alert against the articles stored in
+// the last days days and queues notifications for them, either individually
+// or as one digest. Articles the alert has already been notified about,
+// e.g. by a monitoring run that finished meanwhile, are not sent again.
+func BackfillAlert(app *models.App, alert models.UserAlert, days int, delivery string) (BackfillResult, error) {
+	result := BackfillResult{Days: days, Delivery: delivery}
+
+	q, err := AlertQuery(alert)
+	if err != nil {
+		return result, err
+	}
+
+	var matches []models.Article
+	since := time.Now().AddDate(0, 0, -days)
+	result.ArticlesScanned, err = scanAlertMatches(app, alert, q, since, func(article models.Article, _ string) {
+		matches = append(matches, article)
+	})
+	if err != nil {
+		return result, err
+	}
+	result.Matches = len(matches)
+	if len(matches) == 0 {
+		return result, nil
+	}
+
+	if delivery == BackfillDigest {
+		// Queue the matches as digest items and cut the digest immediately,
+		// whatever the alert's regular delivery mode is
+		digestAlert := alert
+		digestAlert.DeliveryMode = DeliveryDaily
+		if err := EnqueueNotifications(app.DB, digestAlert, matches); err != nil {
+			return result, err
+		}
+
+		backfillAlert := alert
+		backfillAlert.DeliveryMode = BackfillDigest
+		digest, err := createDigest(app.DB, backfillAlert, time.Now())
+		if err != nil {
+			return result, err
+		}
+		if digest != nil {
+			result.DigestID = &digest.ID
+		}
+	} else if err := EnqueueNotifications(app.DB, alert, matches); err != nil {
+		return result, err
+	}
+
+	log.Printf("Backfilled alert %d with %d matches from the last %d days", alert.ID, len(matches), days)
+
+	go func() {
+		if _, err := DispatchNotifications(app); err != nil {
+			log.Printf("Error dispatching notifications: %v", err)
+		}
+	}()
+
+	return result, nil
+}
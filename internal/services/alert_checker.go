@@ -6,93 +6,165 @@ import (
 	"time"
 
 	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"github.com/mrrobotisreal/rss_today_api/internal/query"
+	"gorm.io/gorm"
 )
 
-func CheckAlertsForNewArticles(app *models.App, articles []models.Article) error {
-	// Get all active user alerts
+// CheckAlertsForNewArticles matches new articles against every active alert,
+// returning the number of alert/article matches
+func CheckAlertsForNewArticles(app *models.App, articles []models.Article) (int, error) {
+	// Get all active article-matching alerts that aren't snoozed; spike alerts
+	// are checked on their own schedule by CheckSpikeAlerts
 	var alerts []models.UserAlert
-	if err := app.DB.Where("active = ?", true).Find(&alerts).Error; err != nil {
-		return err
+	err := app.DB.Where("type = ? AND active = ? AND (snoozed_until IS NULL OR snoozed_until <= ?)", AlertTypeMatch, true, time.Now()).
+		Find(&alerts).Error
+	if err != nil {
+		return 0, err
 	}
 
 	log.Printf("Checking %d alerts against %d new articles", len(alerts), len(articles))
 
+	matched := 0
+	sourceNames := loadSourceNames(app, articles)
+
 	for _, alert := range alerts {
-		matchingArticles := findMatchingArticles(articles, alert)
+		matchingArticles := findMatchingArticles(articles, alert, sourceNames)
 
 		if len(matchingArticles) > 0 {
 			log.Printf("Alert ID %d matched %d articles", alert.ID, len(matchingArticles))
+			matched += len(matchingArticles)
 
-			// Here you would typically send notifications
-			// For now, we'll just log and create notification records
-			for _, article := range matchingArticles {
-				notification := models.NotificationSent{
-					UserID:    alert.UserID,
-					ArticleID: article.ID,
-					AlertID:   alert.ID,
-					Method:    "email", // or whatever method you prefer
-					SentAt:    time.Now(),
-				}
-
-				if err := app.DB.Create(&notification).Error; err != nil {
-					log.Printf("Error creating notification record: %v", err)
-				} else {
-					log.Printf("Created notification for user %d, article: %s", alert.UserID, article.Title)
-				}
+			// Record the matches and queue their delivery together, so a crash or a
+			// flaky channel can't lose a notification
+			if err := EnqueueNotifications(app.DB, alert, matchingArticles); err != nil {
+				log.Printf("Error queueing notifications for alert %d: %v", alert.ID, err)
 			}
 		}
 	}
 
-	return nil
+	if matched > 0 {
+		// Deliver right away instead of waiting for the dispatcher's next tick
+		go func() {
+			if _, err := DispatchNotifications(app); err != nil {
+				log.Printf("Error dispatching notifications: %v", err)
+			}
+		}()
+	}
+
+	return matched, nil
 }
 
-func findMatchingArticles(articles []models.Article, alert models.UserAlert) []models.Article {
-	var matchingArticles []models.Article
+// alertMethods returns the channels an alert wants, defaulting to email
+func alertMethods(alert models.UserAlert) []string {
+	if len(alert.NotificationMethods) == 0 {
+		return []string{"email"}
+	}
+	return removeDuplicates(alert.NotificationMethods)
+}
+
+// AlertQuery compiles the query an alert matches with. Alerts without a Query
+// match any of their Keywords as a substring, as keyword alerts always have,
+// so "ukrain" still matches "Ukraine"; a nil query (no keywords either)
+// matches every article.
+func AlertQuery(alert models.UserAlert) (*query.Query, error) {
+	if strings.TrimSpace(alert.Query) != "" {
+		return query.Parse(alert.Query)
+	}
+
+	// Built directly rather than parsed, so no keyword list is too long
+	return query.AnySubstring(alert.Keywords), nil
+}
 
+// articleDocument is the text of article an alert query is matched against
+func articleDocument(article models.Article, sourceName string) query.Document {
+	return query.Document{
+		Title:       article.Title,
+		Description: article.Description,
+		Keywords:    article.Keywords,
+		Content:     article.Content,
+		Source:      sourceName,
+	}
+}
+
+// loadSourceNames maps the sources of articles to their names, including
+// sources deleted since
+func loadSourceNames(app *models.App, articles []models.Article) map[uint]string {
+	names := map[uint]string{}
+
+	var ids []uint
 	for _, article := range articles {
-		if articleMatchesAlert(article, alert) {
-			matchingArticles = append(matchingArticles, article)
+		if article.Source.ID != 0 {
+			names[article.SourceID] = article.Source.Name
+		} else if _, ok := names[article.SourceID]; !ok {
+			names[article.SourceID] = ""
+			ids = append(ids, article.SourceID)
 		}
 	}
 
-	return matchingArticles
+	if len(ids) > 0 {
+		var sources []models.NewsSource
+		if err := app.DB.Unscoped().Select("id", "name").Where("id IN ?", ids).Find(&sources).Error; err != nil {
+			log.Printf("Error loading source names for alert matching: %v", err)
+		}
+		for _, source := range sources {
+			names[source.ID] = source.Name
+		}
+	}
+
+	return names
 }
 
-func articleMatchesAlert(article models.Article, alert models.UserAlert) bool {
-	// Check if any alert keywords match article keywords
-	if len(alert.Keywords) > 0 {
-		keywordMatch := false
-		for _, alertKeyword := range alert.Keywords {
-			alertKeywordLower := strings.ToLower(alertKeyword)
-
-			// Check in article keywords
-			for _, articleKeyword := range article.Keywords {
-				if strings.Contains(strings.ToLower(articleKeyword), alertKeywordLower) {
-					keywordMatch = true
-					break
-				}
-			}
+// scanBatchSize is how many stored articles scanAlertMatches loads per query
+const scanBatchSize = 1000
 
-			if keywordMatch {
-				break
-			}
+// scanAlertMatches runs alert over the articles stored since since, oldest
+// first, calling fn for each match. It returns how many articles were scanned.
+func scanAlertMatches(app *models.App, alert models.UserAlert, q *query.Query, since time.Time, fn func(article models.Article, sourceName string)) (int, error) {
+	scope := app.DB.Model(&models.Article{}).Where("created_at >= ?", since)
+	if len(alert.SourceIDs) > 0 {
+		scope = scope.Where("source_id IN ?", []int64(alert.SourceIDs))
+	}
 
-			// Check in article title and description
-			titleLower := strings.ToLower(article.Title)
-			descLower := strings.ToLower(article.Description)
+	scanned := 0
+	sourceNames := map[uint]string{}
 
-			if strings.Contains(titleLower, alertKeywordLower) ||
-			   strings.Contains(descLower, alertKeywordLower) {
-				keywordMatch = true
-				break
+	var batch []models.Article
+	result := scope.FindInBatches(&batch, scanBatchSize, func(tx *gorm.DB, _ int) error {
+		for id, name := range loadSourceNames(app, batch) {
+			sourceNames[id] = name
+		}
+
+		for _, article := range batch {
+			scanned++
+			if articleMatchesAlert(article, alert, q, sourceNames[article.SourceID]) {
+				fn(article, sourceNames[article.SourceID])
 			}
 		}
+		return nil
+	})
+	return scanned, result.Error
+}
 
-		if !keywordMatch {
-			return false
+func findMatchingArticles(articles []models.Article, alert models.UserAlert, sourceNames map[uint]string) []models.Article {
+	q, err := AlertQuery(alert)
+	if err != nil {
+		// Queries are validated when saved, so this only catches rows edited by hand
+		log.Printf("Skipping alert %d with invalid query: %v", alert.ID, err)
+		return nil
+	}
+
+	var matchingArticles []models.Article
+
+	for _, article := range articles {
+		if articleMatchesAlert(article, alert, q, sourceNames[article.SourceID]) {
+			matchingArticles = append(matchingArticles, article)
 		}
 	}
 
+	return matchingArticles
+}
+
+func articleMatchesAlert(article models.Article, alert models.UserAlert, q *query.Query, sourceName string) bool {
 	// Check source filter if specified
 	if len(alert.SourceIDs) > 0 {
 		sourceMatch := false
@@ -107,5 +179,5 @@ func articleMatchesAlert(article models.Article, alert models.UserAlert) bool {
 		}
 	}
 
-	return true
-}
\ No newline at end of file
+	return q == nil || q.Match(articleDocument(article, sourceName))
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+)
+
+func TestArticleMatchesAlert(t *testing.T) {
+	article := models.Article{
+		SourceID:    1,
+		Title:       "Ukraine talks resume in Geneva",
+		Description: "Diplomats met to discuss the pineapple trade and U.S. climate-change pledges.",
+		Keywords:    []string{"ukraine", "diplomacy"},
+	}
+
+	tests := []struct {
+		name  string
+		alert models.UserAlert
+		want  bool
+	}{
+		{"keyword prefix", models.UserAlert{Keywords: []string{"ukrain"}}, true},
+		{"keyword inside a word", models.UserAlert{Keywords: []string{"apple"}}, true},
+		{"keyword ignores case", models.UserAlert{Keywords: []string{"GENEVA"}}, true},
+		{"keyword phrase", models.UserAlert{Keywords: []string{"talks resume"}}, true},
+		{"keyword phrase inside words", models.UserAlert{Keywords: []string{"ate-change"}}, true},
+		{"keyword punctuation", models.UserAlert{Keywords: []string{"u.s."}}, true},
+		{"keyword with quotes and wildcards", models.UserAlert{Keywords: []string{`"gene*`}}, true},
+		{"any keyword", models.UserAlert{Keywords: []string{"russia", "diplomat"}}, true},
+		{"no keyword", models.UserAlert{Keywords: []string{"russia"}}, false},
+		{"no keywords or query", models.UserAlert{}, true},
+		{"source filter", models.UserAlert{Keywords: []string{"ukraine"}, SourceIDs: []int64{2}}, false},
+		{"query is whole-word", models.UserAlert{Keywords: []string{"ukrain"}, Query: "ukrain"}, false},
+		{"query", models.UserAlert{Query: "ukraine AND NOT russia"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := AlertQuery(tt.alert)
+			if err != nil {
+				t.Fatalf("AlertQuery returned error: %v", err)
+			}
+			if got := articleMatchesAlert(article, tt.alert, q, ""); got != tt.want {
+				t.Errorf("articleMatchesAlert = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlertQueryManyKeywords(t *testing.T) {
+	// Far past query.MaxLength once joined; keyword alerts have no length limit
+	var keywords []string
+	for i := 0; i < 500; i++ {
+		keywords = append(keywords, fmt.Sprintf("unrelated keyword %d", i))
+	}
+	keywords = append(keywords, "geneva")
+	alert := models.UserAlert{Keywords: keywords}
+
+	q, err := AlertQuery(alert)
+	if err != nil {
+		t.Fatalf("AlertQuery returned error: %v", err)
+	}
+	if q == nil {
+		t.Fatal("AlertQuery returned no query")
+	}
+
+	matching := models.Article{Title: "Talks resume in Geneva"}
+	if !articleMatchesAlert(matching, alert, q, "") {
+		t.Error("article containing the last keyword didn't match")
+	}
+	other := models.Article{Title: "Talks resume in Vienna"}
+	if articleMatchesAlert(other, alert, q, "") {
+		t.Error("article containing none of the keywords matched")
+	}
+}
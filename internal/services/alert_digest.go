@@ -0,0 +1,320 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Alert delivery modes
+const (
+	DeliveryInstant = "instant"
+	DeliveryHourly  = "hourly"
+	DeliveryDaily   = "daily"
+	DeliveryWeekly  = "weekly"
+)
+
+// digestMaxArticles caps how many matches one digest renders; the rest are
+// summarised as a count
+const digestMaxArticles = 100
+
+var digestWeekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// IsDigestMode reports whether alerts in mode batch their matches
+func IsDigestMode(mode string) bool {
+	return mode == DeliveryHourly || mode == DeliveryDaily || mode == DeliveryWeekly
+}
+
+// ValidateDeliverySchedule checks an alert's delivery mode, digest time and weekday,
+// filling in defaults for the ones left empty
+func ValidateDeliverySchedule(alert *models.UserAlert) error {
+	if alert.DeliveryMode == "" {
+		alert.DeliveryMode = DeliveryInstant
+	}
+	if alert.DeliveryMode != DeliveryInstant && !IsDigestMode(alert.DeliveryMode) {
+		return fmt.Errorf("delivery_mode must be one of instant, hourly, daily or weekly")
+	}
+
+	if alert.DigestTime == "" {
+		alert.DigestTime = "08:00"
+	}
+	if _, _, err := parseDigestTime(alert.DigestTime); err != nil {
+		return err
+	}
+
+	if alert.DigestWeekday == "" {
+		alert.DigestWeekday = "monday"
+	}
+	alert.DigestWeekday = strings.ToLower(alert.DigestWeekday)
+	if _, ok := digestWeekdays[alert.DigestWeekday]; !ok {
+		return fmt.Errorf("digest_weekday must be a day of the week, e.g. \"monday\"")
+	}
+
+	return nil
+}
+
+// ValidateTimezone checks that name is an IANA zone such as "America/New_York"
+func ValidateTimezone(name string) error {
+	if _, err := time.LoadLocation(name); err != nil || name == "" || name == "Local" {
+		return fmt.Errorf("unknown timezone %q", name)
+	}
+	return nil
+}
+
+// UserLocation resolves a user's timezone, falling back to UTC
+func UserLocation(user models.User) *time.Location {
+	if user.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		log.Printf("Invalid timezone %q for user %d, using UTC", user.Timezone, user.ID)
+		return time.UTC
+	}
+	return loc
+}
+
+// parseDigestTime splits a "HH:MM" digest time
+func parseDigestTime(value string) (int, int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("digest_time must be a 24-hour \"HH:MM\" time")
+	}
+	return parsed.Hour(), parsed.Minute(), nil
+}
+
+// lastDigestBoundary is the most recent scheduled send time for alert at or
+// before now, in loc. Matches found before it are due to go out.
+func lastDigestBoundary(alert models.UserAlert, loc *time.Location, now time.Time) time.Time {
+	local := now.In(loc)
+
+	if alert.DeliveryMode == DeliveryHourly {
+		return time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, loc)
+	}
+
+	hour, minute, err := parseDigestTime(alert.DigestTime)
+	if err != nil {
+		hour, minute = 8, 0
+	}
+	boundary := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, loc)
+	if boundary.After(local) {
+		boundary = boundary.AddDate(0, 0, -1)
+	}
+
+	if alert.DeliveryMode == DeliveryWeekly {
+		weekday, ok := digestWeekdays[alert.DigestWeekday]
+		if !ok {
+			weekday = time.Monday
+		}
+		for boundary.Weekday() != weekday {
+			boundary = boundary.AddDate(0, 0, -1)
+		}
+	}
+
+	return boundary
+}
+
+// queueDigestItems holds matches for a digest alert until its next send time.
+// Matches already queued for the alert are ignored.
+func queueDigestItems(tx *gorm.DB, alert models.UserAlert, articles []models.Article) error {
+	items := make([]models.DigestItem, 0, len(articles))
+	for _, article := range articles {
+		items = append(items, models.DigestItem{
+			AlertID:   alert.ID,
+			UserID:    alert.UserID,
+			ArticleID: article.ID,
+		})
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&items).Error
+}
+
+// SendDueDigests cuts a digest for every alert whose queued matches have
+// reached a scheduled send time and queues it for delivery, returning how many
+// digests were created. Matches still queued for alerts switched back to
+// instant delivery are flushed straight away.
+func SendDueDigests(app *models.App) (int, error) {
+	var pending []struct {
+		AlertID uint
+		Oldest  time.Time
+	}
+	err := app.DB.Model(&models.DigestItem{}).
+		Select("alert_id, MIN(created_at) AS oldest").
+		Where("digest_id IS NULL").
+		Group("alert_id").
+		Scan(&pending).Error
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	created := 0
+	locations := map[uint]*time.Location{}
+
+	for _, p := range pending {
+		var alert models.UserAlert
+		err := app.DB.Where("id = ? AND active = ? AND (snoozed_until IS NULL OR snoozed_until <= ?)", p.AlertID, true, now).
+			First(&alert).Error
+		if err != nil {
+			// Paused and snoozed alerts keep their matches until they come back
+			continue
+		}
+
+		loc, ok := locations[alert.UserID]
+		if !ok {
+			var user models.User
+			app.DB.First(&user, alert.UserID)
+			loc = UserLocation(user)
+			locations[alert.UserID] = loc
+		}
+
+		if IsDigestMode(alert.DeliveryMode) && !p.Oldest.Before(lastDigestBoundary(alert, loc, now)) {
+			continue
+		}
+
+		digest, err := createDigest(app.DB, alert, now)
+		if err != nil {
+			log.Printf("Error creating digest for alert %d: %v", alert.ID, err)
+			continue
+		}
+		if digest != nil {
+			log.Printf("📰 Queued %s digest %d for alert %d with %d matches", digest.Mode, digest.ID, alert.ID, digest.ArticleCount)
+			created++
+		}
+	}
+
+	if created > 0 {
+		go func() {
+			if _, err := DispatchNotifications(app); err != nil {
+				log.Printf("Error dispatching notifications: %v", err)
+			}
+		}()
+	}
+
+	return created, nil
+}
+
+// createDigest claims alert's queued matches into a new AlertDigest and queues
+// one notification per alert method, all in one transaction. It returns nil if
+// another dispatcher already claimed the matches.
+func createDigest(db *gorm.DB, alert models.UserAlert, now time.Time) (*models.AlertDigest, error) {
+	var digest *models.AlertDigest
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		d := models.AlertDigest{
+			AlertID:     alert.ID,
+			UserID:      alert.UserID,
+			Mode:        alert.DeliveryMode,
+			PeriodStart: now,
+			PeriodEnd:   now,
+		}
+		if err := tx.Create(&d).Error; err != nil {
+			return err
+		}
+
+		var claimed []struct {
+			ArticleID uint
+			CreatedAt time.Time
+		}
+		err := tx.Raw(`
+			UPDATE digest_items SET digest_id = ?
+			WHERE id IN (
+				SELECT id FROM digest_items
+				WHERE alert_id = ? AND digest_id IS NULL
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING article_id, created_at`,
+			d.ID, alert.ID).Scan(&claimed).Error
+		if err != nil {
+			return err
+		}
+		if len(claimed) == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		for _, item := range claimed {
+			if item.CreatedAt.Before(d.PeriodStart) {
+				d.PeriodStart = item.CreatedAt
+			}
+		}
+		d.ArticleCount = len(claimed)
+		if err := tx.Model(&d).Updates(map[string]interface{}{
+			"article_count": d.ArticleCount,
+			"period_start":  d.PeriodStart,
+		}).Error; err != nil {
+			return err
+		}
+
+		for _, method := range alertMethods(alert) {
+			digestID := d.ID
+			notification := models.NotificationSent{
+				UserID:   alert.UserID,
+				AlertID:  alert.ID,
+				Method:   method,
+				DigestID: &digestID,
+			}
+			if err := queueNotification(tx, &notification, now); err != nil {
+				return err
+			}
+		}
+
+		digest = &d
+		return nil
+	})
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return digest, err
+}
+
+// loadDigestArticles returns the articles included in digest, newest first
+func loadDigestArticles(app *models.App, digestID uint) ([]models.Article, error) {
+	var articles []models.Article
+	err := app.DB.Preload("Source", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).
+		Where("id IN (?)", app.DB.Model(&models.DigestItem{}).Select("article_id").Where("digest_id = ?", digestID)).
+		Order("pub_date DESC").
+		Find(&articles).Error
+	return articles, err
+}
+
+// digestPeriodLabel describes the window a digest covers, e.g. "Daily digest"
+func digestPeriodLabel(mode string) string {
+	switch mode {
+	case DeliveryHourly:
+		return "Hourly digest"
+	case DeliveryDaily:
+		return "Daily digest"
+	case DeliveryWeekly:
+		return "Weekly digest"
+	case BackfillDigest:
+		return "Recent matches"
+	default:
+		return "Digest"
+	}
+}
+
+// pluralize returns "1 article" / "3 articles"
+func pluralize(n int, word string) string {
+	if n == 1 {
+		return "1 " + word
+	}
+	return strconv.Itoa(n) + " " + word + "s"
+}
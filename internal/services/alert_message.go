@@ -0,0 +1,223 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"github.com/mrrobotisreal/rss_today_api/internal/notify"
+)
+
+// alertTopic describes what an alert watches for, for use in messages
+func alertTopic(alert models.UserAlert) string {
+	if strings.TrimSpace(alert.Query) != "" {
+		return alert.Query
+	}
+	return strings.Join(alert.Keywords, ", ")
+}
+
+// buildAlertMessage renders the notification for one article matching one alert
+func buildAlertMessage(alert models.UserAlert, article models.Article, source models.NewsSource) notify.Message {
+	sourceName := source.Name
+	if sourceName == "" {
+		sourceName = "RSS Today"
+	}
+
+	subject := fmt.Sprintf("[%s] %s", sourceName, article.Title)
+
+	var text strings.Builder
+	text.WriteString(article.Title + "\n")
+	text.WriteString(sourceName + " - " + article.PubDate.Format("Jan 2, 2006 15:04 MST") + "\n\n")
+	if article.Description != "" {
+		text.WriteString(article.Description + "\n\n")
+	}
+	text.WriteString(article.Link + "\n\n")
+	if topic := alertTopic(alert); topic != "" {
+		text.WriteString("You're receiving this because it matched your alert for: " + topic + "\n")
+	}
+
+	var body strings.Builder
+	body.WriteString("<h2><a href=\"" + html.EscapeString(article.Link) + "\">" + html.EscapeString(article.Title) + "</a></h2>")
+	body.WriteString("<p><em>" + html.EscapeString(sourceName) + " &middot; " + html.EscapeString(article.PubDate.Format("Jan 2, 2006 15:04 MST")) + "</em></p>")
+	if article.Description != "" {
+		body.WriteString("<p>" + html.EscapeString(article.Description) + "</p>")
+	}
+	if topic := alertTopic(alert); topic != "" {
+		body.WriteString("<p style=\"color:#666;font-size:12px\">Matched your alert for: " + html.EscapeString(topic) + "</p>")
+	}
+
+	return notify.Message{
+		Subject: subject,
+		Text:    text.String(),
+		HTML:    body.String(),
+		URL:     article.Link,
+		Data: map[string]string{
+			"alert_id":    strconv.FormatUint(uint64(alert.ID), 10),
+			"article_id":  strconv.FormatUint(uint64(article.ID), 10),
+			"source_id":   strconv.FormatUint(uint64(article.SourceID), 10),
+			"source_name": sourceName,
+			"title":       article.Title,
+		},
+	}
+}
+
+// buildDigestMessage renders a digest of articles matching alert, grouped by
+// source, with times shown in loc
+func buildDigestMessage(alert models.UserAlert, digest models.AlertDigest, articles []models.Article, loc *time.Location) notify.Message {
+	label := digestPeriodLabel(digest.Mode)
+	topic := alertTopic(alert)
+	if topic == "" {
+		topic = "your alert"
+	}
+
+	subject := fmt.Sprintf("%s: %s for %s", label, pluralize(digest.ArticleCount, "new article"), topic)
+
+	shown := articles
+	if len(shown) > digestMaxArticles {
+		shown = shown[:digestMaxArticles]
+	}
+
+	// Group by source, keeping sources in order of their newest article
+	var sourceOrder []uint
+	bySource := map[uint][]models.Article{}
+	sourceNames := map[uint]string{}
+	for _, article := range shown {
+		if _, ok := bySource[article.SourceID]; !ok {
+			sourceOrder = append(sourceOrder, article.SourceID)
+			sourceNames[article.SourceID] = article.Source.Name
+			if sourceNames[article.SourceID] == "" {
+				sourceNames[article.SourceID] = "RSS Today"
+			}
+		}
+		bySource[article.SourceID] = append(bySource[article.SourceID], article)
+	}
+
+	period := digest.PeriodStart.In(loc).Format("Jan 2, 15:04") + " - " + digest.PeriodEnd.In(loc).Format("Jan 2, 15:04 MST")
+
+	var text strings.Builder
+	text.WriteString(subject + "\n")
+	text.WriteString(period + "\n\n")
+
+	var body strings.Builder
+	body.WriteString("<h2>" + html.EscapeString(subject) + "</h2>")
+	body.WriteString("<p style=\"color:#666\">" + html.EscapeString(period) + "</p>")
+
+	for _, sourceID := range sourceOrder {
+		name := sourceNames[sourceID]
+		sourceArticles := bySource[sourceID]
+
+		text.WriteString("== " + name + " (" + strconv.Itoa(len(sourceArticles)) + ") ==\n")
+		body.WriteString("<h3>" + html.EscapeString(name) + "</h3><ul>")
+
+		for _, article := range sourceArticles {
+			published := article.PubDate.In(loc).Format("Jan 2 15:04")
+
+			text.WriteString("- " + article.Title + " (" + published + ")\n  " + article.Link + "\n")
+			body.WriteString("<li><a href=\"" + html.EscapeString(article.Link) + "\">" + html.EscapeString(article.Title) + "</a>")
+			body.WriteString(" <span style=\"color:#666;font-size:12px\">" + html.EscapeString(published) + "</span></li>")
+		}
+
+		text.WriteString("\n")
+		body.WriteString("</ul>")
+	}
+
+	if hidden := digest.ArticleCount - len(shown); hidden > 0 {
+		text.WriteString("...and " + pluralize(hidden, "more article") + ".\n\n")
+		body.WriteString("<p>...and " + html.EscapeString(pluralize(hidden, "more article")) + ".</p>")
+	}
+
+	text.WriteString("You're receiving this " + strings.ToLower(label) + " for your alert on: " + topic + "\n")
+	body.WriteString("<p style=\"color:#666;font-size:12px\">You're receiving this " + html.EscapeString(strings.ToLower(label)) + " for your alert on: " + html.EscapeString(topic) + "</p>")
+
+	url := ""
+	if len(shown) > 0 {
+		url = shown[0].Link
+	}
+
+	return notify.Message{
+		Subject: subject,
+		Text:    text.String(),
+		HTML:    body.String(),
+		URL:     url,
+		Data: map[string]string{
+			"alert_id":      strconv.FormatUint(uint64(alert.ID), 10),
+			"digest_id":     strconv.FormatUint(uint64(digest.ID), 10),
+			"delivery_mode": digest.Mode,
+			"article_count": strconv.Itoa(digest.ArticleCount),
+		},
+	}
+}
+
+// buildSpikeMessage renders a coverage spike notification listing the newest
+// articles in the spike's window, with times shown in loc
+func buildSpikeMessage(alert models.UserAlert, event models.SpikeEvent, articles []models.Article, loc *time.Location) notify.Message {
+	window := event.WindowEnd.Sub(event.WindowStart).Round(time.Minute)
+
+	comparison := "with no coverage in the baseline period"
+	if event.Ratio > 0 {
+		comparison = fmt.Sprintf("%.1fx the usual %.1f per window", event.Ratio, event.Baseline)
+	}
+
+	subject := fmt.Sprintf("Coverage spike: %s - %s in the %s", event.Label, pluralize(event.Count, "article"), formatWindow(window))
+	summary := fmt.Sprintf("%s about %s in the %s (up to %s), %s.",
+		pluralize(event.Count, "article"), event.Label, formatWindow(window),
+		event.WindowEnd.In(loc).Format("Jan 2 15:04 MST"), comparison)
+
+	var text strings.Builder
+	text.WriteString(subject + "\n\n" + summary + "\n\n")
+
+	var body strings.Builder
+	body.WriteString("<h2>" + html.EscapeString(subject) + "</h2>")
+	body.WriteString("<p>" + html.EscapeString(summary) + "</p>")
+
+	if len(articles) > 0 {
+		text.WriteString("Latest articles:\n")
+		body.WriteString("<ul>")
+		for _, article := range articles {
+			sourceName := article.Source.Name
+			if sourceName == "" {
+				sourceName = "RSS Today"
+			}
+			text.WriteString("- " + article.Title + " (" + sourceName + ")\n  " + article.Link + "\n")
+			body.WriteString("<li><a href=\"" + html.EscapeString(article.Link) + "\">" + html.EscapeString(article.Title) + "</a>")
+			body.WriteString(" <span style=\"color:#666;font-size:12px\">" + html.EscapeString(sourceName) + "</span></li>")
+		}
+		body.WriteString("</ul>")
+	}
+
+	url := ""
+	if len(articles) > 0 {
+		url = articles[0].Link
+	}
+
+	return notify.Message{
+		Subject: subject,
+		Text:    text.String(),
+		HTML:    body.String(),
+		URL:     url,
+		Data: map[string]string{
+			"alert_id": strconv.FormatUint(uint64(alert.ID), 10),
+			"spike_id": strconv.FormatUint(uint64(event.ID), 10),
+			"series":   event.Series,
+			"label":    event.Label,
+			"count":    strconv.Itoa(event.Count),
+			"baseline": strconv.FormatFloat(event.Baseline, 'f', 2, 64),
+			"ratio":    strconv.FormatFloat(event.Ratio, 'f', 2, 64),
+		},
+	}
+}
+
+// formatWindow describes a spike window, e.g. "last hour" or "last 30 minutes"
+func formatWindow(window time.Duration) string {
+	switch {
+	case window == time.Hour:
+		return "last hour"
+	case window%time.Hour == 0:
+		return "last " + pluralize(int(window/time.Hour), "hour")
+	default:
+		return "last " + pluralize(int(window/time.Minute), "minute")
+	}
+}
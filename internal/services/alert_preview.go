@@ -0,0 +1,111 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// PreviewMaxDays is the furthest back an alert preview may look
+	PreviewMaxDays = 90
+	// PreviewMaxSamples caps how many matching articles a preview returns
+	PreviewMaxSamples = 50
+)
+
+// AlertPreview summarises what an alert would have matched over a past window
+type AlertPreview struct {
+	Query           string               `json:"query,omitempty"` // Canonical form of the query used
+	Days            int                  `json:"days"`
+	Since           time.Time            `json:"since"`
+	ArticlesScanned int                  `json:"articles_scanned"`
+	TotalMatches    int                  `json:"total_matches"`
+	AveragePerDay   float64              `json:"average_per_day"`
+	ByDay           []PreviewDayCount    `json:"by_day"`
+	BySource        []PreviewSourceCount `json:"by_source"`
+	Samples         []models.Article     `json:"samples"`
+}
+
+type PreviewDayCount struct {
+	Date  string `json:"date"` // YYYY-MM-DD in the user's timezone
+	Count int    `json:"count"`
+}
+
+type PreviewSourceCount struct {
+	SourceID   uint   `json:"source_id"`
+	SourceName string `json:"source_name"`
+	Count      int    `json:"count"`
+}
+
+// PreviewAlert runs alert's matching over the articles stored in the last days
+// days without saving or notifying anything. Days are bucketed in loc by when
+// the article was found, which is when the alert would have fired.
+func PreviewAlert(app *models.App, alert models.UserAlert, days, samples int, loc *time.Location) (AlertPreview, error) {
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	since := today.AddDate(0, 0, -(days - 1))
+
+	preview := AlertPreview{Days: days, Since: since}
+
+	q, err := AlertQuery(alert)
+	if err != nil {
+		return preview, err
+	}
+	if q != nil {
+		preview.Query = q.String()
+	}
+
+	dayCounts := map[string]int{}
+	sourceCounts := map[uint]int{}
+	sourceNames := map[uint]string{}
+	var sampleIDs []uint
+
+	scanned, err := scanAlertMatches(app, alert, q, since, func(article models.Article, sourceName string) {
+		preview.TotalMatches++
+		dayCounts[article.CreatedAt.In(loc).Format("2006-01-02")]++
+		sourceCounts[article.SourceID]++
+		sourceNames[article.SourceID] = sourceName
+
+		// Matches arrive oldest first, so keep the latest ones as samples
+		sampleIDs = append(sampleIDs, article.ID)
+		if len(sampleIDs) > samples {
+			sampleIDs = sampleIDs[1:]
+		}
+	})
+	preview.ArticlesScanned = scanned
+	if err != nil {
+		return preview, err
+	}
+
+	// Every day in the window is listed, including the quiet ones
+	for day := since; !day.After(today); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		preview.ByDay = append(preview.ByDay, PreviewDayCount{Date: date, Count: dayCounts[date]})
+	}
+	preview.AveragePerDay = float64(preview.TotalMatches) / float64(days)
+
+	preview.BySource = []PreviewSourceCount{}
+	for id, count := range sourceCounts {
+		preview.BySource = append(preview.BySource, PreviewSourceCount{SourceID: id, SourceName: sourceNames[id], Count: count})
+	}
+	sort.Slice(preview.BySource, func(i, j int) bool {
+		if preview.BySource[i].Count != preview.BySource[j].Count {
+			return preview.BySource[i].Count > preview.BySource[j].Count
+		}
+		return preview.BySource[i].SourceName < preview.BySource[j].SourceName
+	})
+
+	preview.Samples = []models.Article{}
+	if len(sampleIDs) > 0 {
+		err := app.DB.Preload("Source", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).Where("id IN ?", sampleIDs).Order("id DESC").Find(&preview.Samples).Error
+		if err != nil {
+			return preview, err
+		}
+	}
+
+	return preview, nil
+}
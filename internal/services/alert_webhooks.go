@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"github.com/mrrobotisreal/rss_today_api/internal/notify"
+)
+
+// Webhook payloads carry this version so receivers can handle future format changes
+const WebhookPayloadVersion = "1"
+
+// Webhook events
+const (
+	WebhookEventAlertMatched = "alert.matched"
+	WebhookEventAlertSpike   = "alert.spike"
+	WebhookEventTest         = "webhook.test"
+)
+
+// MethodAlertWebhook is the NotificationSent method for deliveries to a user's own webhook
+const MethodAlertWebhook = "alert_webhook"
+
+// webhookClient only connects to public addresses, checked at dial time and
+// on every redirect, since users choose where webhooks point
+var webhookClient = &http.Client{
+	Timeout:       10 * time.Second,
+	Transport:     newPublicTransport(),
+	CheckRedirect: checkPublicRedirect,
+}
+
+// WebhookPayload is the JSON body POSTed to user webhooks
+type WebhookPayload struct {
+	Version   string             `json:"version"`
+	ID        string             `json:"id"` // Event ID; identical across retries so receivers can dedupe
+	Event     string             `json:"event"`
+	CreatedAt time.Time          `json:"created_at"`
+	Alert     WebhookAlert       `json:"alert"`
+	Article   *models.Article    `json:"article,omitempty"` // alert.matched and webhook.test
+	Source    *models.NewsSource `json:"source,omitempty"`
+	Spike     *WebhookSpike      `json:"spike,omitempty"` // alert.spike
+}
+
+// WebhookSpike describes a coverage spike in alert.spike payloads
+type WebhookSpike struct {
+	ID          uint             `json:"id"`
+	Series      string           `json:"series"` // Keyword, or "source:<id>"
+	Label       string           `json:"label"`
+	WindowStart time.Time        `json:"window_start"`
+	WindowEnd   time.Time        `json:"window_end"`
+	Count       int              `json:"count"`
+	Baseline    float64          `json:"baseline"`
+	Ratio       float64          `json:"ratio"` // 0 when there was no coverage in the baseline period
+	Articles    []models.Article `json:"articles"`
+}
+
+// WebhookAlert is the alert metadata included in webhook payloads
+type WebhookAlert struct {
+	ID        uint     `json:"id"`
+	Keywords  []string `json:"keywords"`
+	Query     string   `json:"query,omitempty"`
+	SourceIDs []int64  `json:"source_ids"`
+}
+
+// GenerateWebhookSecret returns a new random signing secret
+func GenerateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+// DeliverAlertWebhook POSTs one signed article event to webhook and logs the attempt.
+// It returns a notify status so it can be driven by the notification outbox.
+func DeliverAlertWebhook(app *models.App, webhook models.AlertWebhook, event, eventID string, alert models.UserAlert, article models.Article) (models.WebhookDelivery, string, error) {
+	payload := newWebhookPayload(event, eventID, alert)
+	payload.Article = &article
+	if article.Source.ID != 0 {
+		source := article.Source
+		payload.Source = &source
+		// Already in Source; don't send it twice
+		payload.Article.Source = models.NewsSource{}
+	}
+
+	return postAlertWebhook(app, webhook, payload, article.ID)
+}
+
+// newWebhookPayload fills in the fields every webhook event shares
+func newWebhookPayload(event, eventID string, alert models.UserAlert) WebhookPayload {
+	return WebhookPayload{
+		Version:   WebhookPayloadVersion,
+		ID:        eventID,
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Alert: WebhookAlert{
+			ID:        alert.ID,
+			Keywords:  alert.Keywords,
+			Query:     alert.Query,
+			SourceIDs: alert.SourceIDs,
+		},
+	}
+}
+
+// postAlertWebhook signs and sends payload to webhook, logging a WebhookDelivery
+func postAlertWebhook(app *models.App, webhook models.AlertWebhook, payload WebhookPayload, articleID uint) (models.WebhookDelivery, string, error) {
+	delivery := models.WebhookDelivery{
+		WebhookID: webhook.ID,
+		EventID:   payload.ID,
+		Event:     payload.Event,
+		ArticleID: articleID,
+	}
+
+	if !webhook.Active {
+		return delivery, notify.StatusSkipped, fmt.Errorf("webhook %d is disabled", webhook.ID)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return delivery, notify.StatusFailed, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+	defer cancel()
+
+	response, err := notify.PostSigned(ctx, webhookClient, webhook.URL, webhook.Secret, payload.Event, body)
+	delivery.StatusCode = response.StatusCode
+	delivery.DurationMs = response.Duration.Milliseconds()
+
+	status := notify.StatusSent
+	if err == nil && (response.StatusCode < 200 || response.StatusCode >= 300) {
+		err = fmt.Errorf("webhook returned HTTP %d", response.StatusCode)
+	}
+	if err != nil {
+		status = notify.StatusFailed
+		delivery.Error = err.Error()
+	} else {
+		delivery.Success = true
+	}
+
+	if dbErr := app.DB.Create(&delivery).Error; dbErr != nil {
+		log.Printf("Error logging webhook delivery for webhook %d: %v", webhook.ID, dbErr)
+	}
+
+	return delivery, status, err
+}
+
+// deliverToAlertWebhook is the outbox path for MethodAlertWebhook notifications
+func deliverToAlertWebhook(app *models.App, notification models.NotificationSent, alert models.UserAlert, article models.Article) (string, error) {
+	webhook, status, err := loadNotificationWebhook(app, notification)
+	if err != nil {
+		return status, err
+	}
+
+	eventID := fmt.Sprintf("evt_%d", notification.ID)
+	_, status, err = DeliverAlertWebhook(app, webhook, WebhookEventAlertMatched, eventID, alert, article)
+	return status, err
+}
+
+// deliverSpikeToAlertWebhook is the outbox path for MethodAlertWebhook spike notifications
+func deliverSpikeToAlertWebhook(app *models.App, notification models.NotificationSent, alert models.UserAlert, event models.SpikeEvent, articles []models.Article) (string, error) {
+	webhook, status, err := loadNotificationWebhook(app, notification)
+	if err != nil {
+		return status, err
+	}
+
+	payload := newWebhookPayload(WebhookEventAlertSpike, fmt.Sprintf("evt_%d", notification.ID), alert)
+	payload.Spike = &WebhookSpike{
+		ID:          event.ID,
+		Series:      event.Series,
+		Label:       event.Label,
+		WindowStart: event.WindowStart,
+		WindowEnd:   event.WindowEnd,
+		Count:       event.Count,
+		Baseline:    event.Baseline,
+		Ratio:       event.Ratio,
+		Articles:    articles,
+	}
+
+	_, status, err = postAlertWebhook(app, webhook, payload, 0)
+	return status, err
+}
+
+// loadNotificationWebhook fetches the AlertWebhook a notification targets,
+// with the delivery status to record if it can't
+func loadNotificationWebhook(app *models.App, notification models.NotificationSent) (models.AlertWebhook, string, error) {
+	var webhook models.AlertWebhook
+	if notification.WebhookID == nil {
+		return webhook, notify.StatusSkipped, fmt.Errorf("notification has no webhook")
+	}
+	if err := app.DB.First(&webhook, *notification.WebhookID).Error; err != nil {
+		status, err := lookupStatus(err, "webhook", *notification.WebhookID)
+		return webhook, status, err
+	}
+	return webhook, "", nil
+}
+
+// SendTestWebhook sends a webhook.test event using the most recent article
+// (or a placeholder when there are none) so users can check their receiver
+func SendTestWebhook(app *models.App, webhook models.AlertWebhook, alert models.UserAlert) (models.WebhookDelivery, error) {
+	var article models.Article
+	if err := app.DB.Preload("Source").Order("created_at DESC").First(&article).Error; err != nil {
+		article = models.Article{
+			Title:       "Test article from RSS Today",
+			Description: "This is a test event. No real article matched.",
+			Link:        "https://example.com/rss-today-test",
+			PubDate:     time.Now(),
+		}
+	}
+
+	suffix := make([]byte, 8)
+	rand.Read(suffix)
+	eventID := "evt_test_" + hex.EncodeToString(suffix)
+
+	// Send even if the webhook is paused - the user explicitly asked for it
+	webhook.Active = true
+	delivery, _, err := DeliverAlertWebhook(app, webhook, WebhookEventTest, eventID, alert, article)
+	return delivery, err
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/lib/pq"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"gorm.io/gorm"
+)
+
+// Near-duplicate detection settings
+const (
+	// clusterWindow is how far either side of an article's publication date
+	// near-duplicates are looked for
+	clusterWindow = 48 * time.Hour
+	// maxSimHashDistance is how many of the 64 fingerprint bits two articles
+	// may differ in and still count as the same story. Lightly edited copies
+	// of a headline and teaser land around 5-7; different stories on the same
+	// topic around 20 or more.
+	maxSimHashDistance = 8
+	// minSimHashFeatures is the fewest words worth fingerprinting; shorter
+	// texts collide too easily
+	minSimHashFeatures = 4
+	// simHashBandCount is how many bands fingerprints are split into for
+	// finding candidates; see simHashBands
+	simHashBandCount = 8
+)
+
+// clusterCandidate is what clusterArticle reads about recent articles
+type clusterCandidate struct {
+	ID        uint
+	SimHash   int64
+	ClusterID *uint
+}
+
+// SimHash fingerprints text so that similar texts get fingerprints differing
+// in few bits. Features are words and pairs of adjacent words, ignoring
+// case, punctuation and stop words. It returns 0 for text too short to fingerprint.
+func SimHash(text string) int64 {
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !isStopWord(word) {
+			words = append(words, word)
+		}
+	}
+	if len(words) < minSimHashFeatures {
+		return 0
+	}
+
+	features := words
+	for i := 0; i+1 < len(words); i++ {
+		features = append(features, words[i]+" "+words[i+1])
+	}
+
+	var weights [64]int
+	for _, feature := range features {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	// Stored in a bigint column, so reinterpret rather than convert
+	return int64(fingerprint)
+}
+
+// simHashDistance is the number of bits two fingerprints differ in
+func simHashDistance(a, b int64) int {
+	return bits.OnesCount64(uint64(a ^ b))
+}
+
+// articleSimHash fingerprints the text every outlet's copy of a story shares
+func articleSimHash(article models.Article) int64 {
+	return SimHash(article.Title + " " + article.Description)
+}
+
+// simHashBands splits a fingerprint into simHashBandCount bands of 8 bits,
+// each tagged with its position so equal bits in different bands don't
+// collide. Two fingerprints within maxSimHashDistance bits of each other
+// almost always share a band: always up to 7 bits apart, and all but about
+// 0.4% of pairs 8 bits apart. Returns nil for an unfingerprinted article.
+func simHashBands(fingerprint int64) pq.Int64Array {
+	if fingerprint == 0 {
+		return nil
+	}
+
+	bands := make(pq.Int64Array, simHashBandCount)
+	for band := range bands {
+		bands[band] = int64(band)<<8 | int64(uint64(fingerprint)>>(8*band)&0xff)
+	}
+	return bands
+}
+
+// clusterArticle links a just-saved article to the closest near-duplicate
+// from another source published around the same time, if any. The first
+// article of a cluster leads it: every member's ClusterID is the leader's ID,
+// and the leader counts its duplicates so lists can collapse them.
+//
+// Assignment is serialized across instances, so two copies of a story saved
+// at once can't each start a cluster of their own.
+func clusterArticle(app *models.App, article *models.Article) error {
+	if article.SimHash == 0 {
+		return nil
+	}
+
+	var leaderID uint
+	err := app.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", LockNamespaceCluster, 0).Error; err != nil {
+			return err
+		}
+
+		// Only articles sharing a band can be close enough; the index on
+		// sim_bands keeps this from scanning the whole window
+		var candidates []clusterCandidate
+		err := tx.Model(&models.Article{}).
+			Select("id", "sim_hash", "cluster_id").
+			Where("sim_bands && ? AND id <> ? AND source_id <> ?", simHashBands(article.SimHash), article.ID, article.SourceID).
+			Where("pub_date BETWEEN ? AND ?", article.PubDate.Add(-clusterWindow), article.PubDate.Add(clusterWindow)).
+			Order("id").
+			Scan(&candidates).Error
+		if err != nil {
+			return err
+		}
+
+		var match *clusterCandidate
+		bestDistance := maxSimHashDistance + 1
+		for i, candidate := range candidates {
+			if distance := simHashDistance(article.SimHash, candidate.SimHash); distance < bestDistance {
+				match, bestDistance = &candidates[i], distance
+			}
+		}
+		if match == nil {
+			return nil
+		}
+
+		leaderID = match.ID
+		if match.ClusterID != nil {
+			leaderID = *match.ClusterID
+		}
+
+		err = tx.Model(&models.Article{}).Where("id = ?", leaderID).
+			UpdateColumns(map[string]interface{}{
+				"cluster_id":      leaderID,
+				"duplicate_count": gorm.Expr("duplicate_count + 1"),
+			}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.Article{}).Where("id = ?", article.ID).UpdateColumn("cluster_id", leaderID).Error
+	})
+	if err != nil || leaderID == 0 {
+		return err
+	}
+
+	article.ClusterID = &leaderID
+	return nil
+}
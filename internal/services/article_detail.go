@@ -0,0 +1,196 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Related article limits
+const (
+	// relatedWindow is how far either side of an article's publication date
+	// related coverage is looked for
+	relatedWindow = 72 * time.Hour
+	// relatedMinOverlap is how many keywords another article must share to
+	// count as related; single words are too common to go on alone
+	relatedMinOverlap = 2
+	relatedLimit      = 10
+)
+
+// ArticleDetail is an article with its source, the user's alerts it matched
+// and matches, and other outlets' coverage of the same story
+type ArticleDetail struct {
+	models.Article
+	MatchedAlerts  []models.UserAlert `json:"matched_alerts"`  // Alerts that matched it when it arrived, and notified the user
+	MatchingAlerts []models.UserAlert `json:"matching_alerts"` // Alerts whose current query matches it, including paused ones
+	Duplicates     []models.Article   `json:"duplicates"`      // Near-identical copies of this story, including the first report
+	Related        []RelatedArticle   `json:"related"`
+}
+
+// RelatedArticle is another source's article sharing keywords with the one being viewed
+type RelatedArticle struct {
+	models.Article
+	SharedKeywords []string `json:"shared_keywords"`
+}
+
+// relatedRow is what findRelatedArticles reads before loading the articles themselves
+type relatedRow struct {
+	ID uint
+}
+
+// GetArticleDetail loads the article with the given ID for userID. It returns
+// gorm.ErrRecordNotFound if there is no such article.
+func GetArticleDetail(app *models.App, id, userID uint) (ArticleDetail, error) {
+	var detail ArticleDetail
+
+	// Unscoped so articles from soft-deleted sources still carry their source
+	err := app.DB.Preload("Source", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).First(&detail.Article, id).Error
+	if err != nil {
+		return detail, err
+	}
+
+	if detail.MatchedAlerts, err = matchedAlerts(app, detail.Article, userID); err != nil {
+		return detail, err
+	}
+	if detail.MatchingAlerts, err = matchingAlerts(app, detail.Article, userID); err != nil {
+		return detail, err
+	}
+	if detail.Duplicates, err = findDuplicates(app, detail.Article); err != nil {
+		return detail, err
+	}
+	if detail.Related, err = findRelatedArticles(app, detail.Article); err != nil {
+		return detail, err
+	}
+	return detail, nil
+}
+
+// matchedAlerts returns the alerts of userID that matched article when it was
+// checked, as recorded by the notifications and digest entries it produced.
+// Queries edited since don't change what actually happened.
+func matchedAlerts(app *models.App, article models.Article, userID uint) ([]models.UserAlert, error) {
+	notified := app.DB.Model(&models.NotificationSent{}).Select("alert_id").
+		Where("user_id = ? AND article_id = ?", userID, article.ID)
+	digested := app.DB.Model(&models.DigestItem{}).Select("alert_id").
+		Where("user_id = ? AND article_id = ?", userID, article.ID)
+
+	alerts := []models.UserAlert{}
+	err := app.DB.Where("user_id = ? AND (id IN (?) OR id IN (?))", userID, notified, digested).
+		Order("id").Find(&alerts).Error
+	return alerts, err
+}
+
+// matchingAlerts returns the match alerts of userID whose current query
+// article satisfies, including paused ones, so the UI can show whether it
+// would be flagged now
+func matchingAlerts(app *models.App, article models.Article, userID uint) ([]models.UserAlert, error) {
+	var alerts []models.UserAlert
+	if err := app.DB.Where("user_id = ? AND type = ?", userID, AlertTypeMatch).Order("id").Find(&alerts).Error; err != nil {
+		return nil, err
+	}
+
+	matched := []models.UserAlert{}
+	for _, alert := range alerts {
+		q, err := AlertQuery(alert)
+		if err != nil {
+			log.Printf("Skipping alert %d with invalid query: %v", alert.ID, err)
+			continue
+		}
+		if articleMatchesAlert(article, alert, q, article.Source.Name) {
+			matched = append(matched, alert)
+		}
+	}
+	return matched, nil
+}
+
+// findDuplicates returns the other articles in article's near-duplicate cluster, oldest first
+func findDuplicates(app *models.App, article models.Article) ([]models.Article, error) {
+	duplicates := []models.Article{}
+	if article.ClusterID == nil {
+		return duplicates, nil
+	}
+
+	err := app.DB.Omit("content").Preload("Source", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Where("cluster_id = ? AND id <> ?", *article.ClusterID, article.ID).Order("id").Find(&duplicates).Error
+	return duplicates, err
+}
+
+// findRelatedArticles returns articles from other sources published around
+// the same time as article, ranked by how many keywords they share with it.
+// Near-duplicates are left out; they're listed separately.
+func findRelatedArticles(app *models.App, article models.Article) ([]RelatedArticle, error) {
+	related := []RelatedArticle{}
+	if len(article.Keywords) < relatedMinOverlap {
+		return related, nil
+	}
+
+	keywords := pq.Array([]string(article.Keywords))
+	overlap := "cardinality(ARRAY(SELECT unnest(keywords) INTERSECT SELECT unnest(?::text[])))"
+
+	var rows []relatedRow
+	err := app.DB.Model(&models.Article{}).
+		Select("id").
+		Where("source_id <> ? AND id <> ?", article.SourceID, article.ID).
+		Where("pub_date BETWEEN ? AND ?", article.PubDate.Add(-relatedWindow), article.PubDate.Add(relatedWindow)).
+		Where("keywords && ?", keywords).
+		Where("cluster_id IS NULL OR cluster_id <> ?", clusterID(article)).
+		Where(overlap+" >= ?", keywords, relatedMinOverlap).
+		Order(clause.OrderBy{Expression: gorm.Expr(overlap+" DESC, pub_date DESC, id DESC", keywords)}).
+		Limit(relatedLimit).
+		Scan(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return related, err
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+
+	var articles []models.Article
+	err = app.DB.Omit("content").Preload("Source", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Where("id IN ?", ids).Find(&articles).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]models.Article, len(articles))
+	for _, a := range articles {
+		byID[a.ID] = a
+	}
+
+	own := make(map[string]bool, len(article.Keywords))
+	for _, keyword := range article.Keywords {
+		own[keyword] = true
+	}
+
+	for _, row := range rows {
+		a, ok := byID[row.ID]
+		if !ok {
+			continue
+		}
+		shared := []string{}
+		for _, keyword := range a.Keywords {
+			if own[keyword] {
+				shared = append(shared, keyword)
+			}
+		}
+		related = append(related, RelatedArticle{Article: a, SharedKeywords: shared})
+	}
+	return related, nil
+}
+
+// clusterID is article's cluster, or 0 (which no cluster has) when it has none
+func clusterID(article models.Article) uint {
+	if article.ClusterID == nil {
+		return 0
+	}
+	return *article.ClusterID
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"html"
+	"strings"
+	"time"
+
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// searchQuery parses a user's query with the same text search configuration
+// the search_vector column is built with
+const searchQuery = "websearch_to_tsquery('english', ?)"
+
+// Sentinels ts_headline wraps matches in. They can't appear in feed text, so
+// the highlighted text can be HTML-escaped safely before they become <mark> tags.
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+)
+
+// ArticleSearchHit is an article matching a full-text search, with its
+// relevance and highlighted text
+type ArticleSearchHit struct {
+	models.Article
+	Rank           float64 `json:"rank"`
+	TitleHighlight string  `json:"title_highlight"` // HTML-escaped title with matches in <mark>
+	Snippet        string  `json:"snippet"`         // HTML-escaped description and body excerpts with matches in <mark>
+}
+
+// searchRow is what SearchArticles reads before loading the articles themselves
+type searchRow struct {
+	ID             uint
+	Rank           float64
+	TitleHighlight string
+	Snippet        string
+}
+
+// ArticleSearchCondition restricts scope to articles matching q, written in
+// websearch syntax: words, "quoted phrases", OR, and -exclusions
+func ArticleSearchCondition(scope *gorm.DB, q string) *gorm.DB {
+	return scope.Where("articles.search_vector @@ "+searchQuery, q)
+}
+
+// OrderByRelevance orders scope by how well articles match q, most relevant
+// first, breaking ties by publication date
+func OrderByRelevance(scope *gorm.DB, q string) *gorm.DB {
+	return scope.Order(clause.OrderBy{Expression: gorm.Expr(
+		"ts_rank_cd(articles.search_vector, "+searchQuery+") DESC, articles.pub_date DESC, articles.id DESC", q)})
+}
+
+// RelevanceAfter restricts scope, ordered with OrderByRelevance, to the
+// articles after the one with the given rank, publication date and ID
+func RelevanceAfter(scope *gorm.DB, q string, rank float64, pubDate time.Time, id uint) *gorm.DB {
+	// Compare as real, the type ts_rank_cd returns, so the cursor's own rank isn't repeated
+	return scope.Where("(ts_rank_cd(articles.search_vector, "+searchQuery+"), articles.pub_date, articles.id) < (?::real, ?, ?)",
+		q, rank, pubDate, id)
+}
+
+// SearchArticles runs scope, which must already be filtered with
+// ArticleSearchCondition and ordered and limited as the caller wants, and
+// returns the matching articles with ranks and highlighted snippets in order
+func SearchArticles(app *models.App, scope *gorm.DB, q string) ([]ArticleSearchHit, error) {
+	selectors := `StartSel="` + highlightStart + `", StopSel="` + highlightStop + `"`
+	titleOptions := selectors + ", HighlightAll=true"
+	snippetOptions := selectors + `, MaxFragments=2, MaxWords=25, MinWords=10, FragmentDelimiter=" … "`
+
+	var rows []searchRow
+	err := scope.Select(
+		"articles.id, "+
+			"ts_rank_cd(articles.search_vector, "+searchQuery+") AS rank, "+
+			"ts_headline('english', articles.title, "+searchQuery+", ?) AS title_highlight, "+
+			"ts_headline('english', concat_ws(' ', articles.description, articles.content), "+searchQuery+", ?) AS snippet",
+		q, q, titleOptions, q, snippetOptions,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]ArticleSearchHit, 0, len(rows))
+	if len(rows) == 0 {
+		return hits, nil
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+
+	var articles []models.Article
+	err = app.DB.Omit("content").Preload("Source", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Where("id IN ?", ids).Find(&articles).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]models.Article, len(articles))
+	for _, article := range articles {
+		byID[article.ID] = article
+	}
+
+	for _, row := range rows {
+		article, ok := byID[row.ID]
+		if !ok {
+			continue
+		}
+		hits = append(hits, ArticleSearchHit{
+			Article:        article,
+			Rank:           row.Rank,
+			TitleHighlight: renderHighlight(row.TitleHighlight),
+			Snippet:        renderHighlight(row.Snippet),
+		})
+	}
+	return hits, nil
+}
+
+// renderHighlight escapes ts_headline output and turns its sentinels into <mark> tags
+func renderHighlight(text string) string {
+	escaped := html.EscapeString(text)
+	escaped = strings.ReplaceAll(escaped, highlightStart, "<mark>")
+	return strings.ReplaceAll(escaped, highlightStop, "</mark>")
+}
@@ -1,14 +1,19 @@
 package services
 
 import (
+	"fmt"
 	"log"
 
 	"github.com/mrrobotisreal/rss_today_api/internal/models"
 	"gorm.io/gorm"
 )
 
+// SaveNewArticles stores the articles not seen before and returns them. If any
+// article couldn't be checked or saved it also returns an error, so the caller
+// knows to fetch the feed again rather than treat it as fully stored.
 func SaveNewArticles(app *models.App, articles []models.Article) ([]models.Article, error) {
 	var newArticles []models.Article
+	failed := 0
 
 	for _, article := range articles {
 		// Check if article already exists by link or content hash
@@ -17,12 +22,22 @@ func SaveNewArticles(app *models.App, articles []models.Article) ([]models.Artic
 
 		if result.Error == gorm.ErrRecordNotFound {
 			// Article is new, save it
+			article.SimHash = articleSimHash(article)
+			article.SimBands = simHashBands(article.SimHash)
 			if err := app.DB.Create(&article).Error; err != nil {
 				log.Printf("Error saving article '%s': %v", article.Title, err)
+				failed++
 				continue
 			}
+			// The same story from another outlet won't match by link or hash
+			if err := clusterArticle(app, &article); err != nil {
+				log.Printf("Error clustering article '%s': %v", article.Title, err)
+			}
 			newArticles = append(newArticles, article)
 			log.Printf("Saved new article: %s", article.Title)
+		} else if result.Error != nil {
+			log.Printf("Error checking article '%s': %v", article.Title, result.Error)
+			failed++
 		}
 	}
 
@@ -30,5 +45,8 @@ func SaveNewArticles(app *models.App, articles []models.Article) ([]models.Artic
 		log.Printf("Saved %d new articles to database", len(newArticles))
 	}
 
+	if failed > 0 {
+		return newArticles, fmt.Errorf("%d of %d articles could not be saved", failed, len(articles))
+	}
 	return newArticles, nil
 }
\ No newline at end of file
@@ -0,0 +1,472 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// Content extraction limits
+const (
+	extractMaxPageSize = 5 << 20 // 5 MiB; article pages bigger than this are mostly scripts
+	// maxExtractPerFetch caps how many pages one feed fetch downloads, so a feed
+	// that suddenly lists hundreds of new items can't tie up its worker
+	maxExtractPerFetch = 25
+	// extractPageTimeout bounds one page download, including the wait for its host
+	extractPageTimeout = 15 * time.Second
+	// minExtractedLength is the shortest body worth keeping; anything less is
+	// usually a cookie wall or paywall blurb
+	minExtractedLength = 250
+	minParagraphLength = 25
+	maxBylineLength    = 100
+)
+
+// ExtractedContent is the main content of an article page
+type ExtractedContent struct {
+	Text      string
+	WordCount int
+	ImageURL  string
+	Byline    string
+}
+
+var (
+	// unlikelyCandidate matches class and id values of page furniture
+	unlikelyCandidate = regexp.MustCompile(`(?i)comment|share|social|related|promo|newsletter|subscribe|sidebar|cookie|banner|breadcrumb|popup|modal|masthead|menu|sponsor|advert`)
+	// maybeCandidate rescues elements that match unlikelyCandidate but look like content
+	maybeCandidate = regexp.MustCompile(`(?i)article|body|content|main|story|column`)
+
+	positiveClass = regexp.MustCompile(`(?i)article|body|content|entry|main|post|story|text|blog`)
+	negativeClass = regexp.MustCompile(`(?i)comment|footer|footnote|meta|outbrain|taboola|promo|related|share|sidebar|social|widget|caption|byline|hidden`)
+
+	bylineClass = regexp.MustCompile(`(?i)byline|author|writer`)
+)
+
+// removedTags never contain article text
+var removedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Nav: true,
+	atom.Header: true, atom.Footer: true, atom.Aside: true, atom.Form: true,
+	atom.Iframe: true, atom.Svg: true, atom.Button: true, atom.Select: true,
+	atom.Template: true, atom.Figcaption: true,
+}
+
+// textBlocks are the elements whose text makes up the extracted body
+var textBlocks = map[atom.Atom]bool{
+	atom.P: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Li: true, atom.Blockquote: true, atom.Pre: true,
+}
+
+// ExtractTimeBudget is the most time one feed fetch spends extracting article
+// pages. It runs while the source's worker is busy, so it is kept well under
+// the poll interval. Override with EXTRACT_TIME_BUDGET.
+func ExtractTimeBudget() time.Duration {
+	return envDuration("EXTRACT_TIME_BUDGET", time.Minute)
+}
+
+// ExtractNewArticleContent downloads the pages of the articles from a feed
+// fetch that aren't stored yet and fills in their body text, lead image and
+// byline, re-extracting keywords to include the body. It stops once
+// ExtractTimeBudget is spent; articles whose page wasn't extracted keep just
+// their feed description.
+func ExtractNewArticleContent(app *models.App, source models.NewsSource, articles []models.Article) {
+	if len(articles) == 0 {
+		return
+	}
+
+	links := make([]string, len(articles))
+	for i, article := range articles {
+		links[i] = article.Link
+	}
+	var stored []string
+	if err := app.DB.Model(&models.Article{}).Where("link IN ?", links).Pluck("link", &stored).Error; err != nil {
+		log.Printf("Error checking stored articles for %s: %v", source.Name, err)
+		return
+	}
+	seen := make(map[string]bool, len(stored))
+	for _, link := range stored {
+		seen[link] = true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ExtractTimeBudget())
+	defer cancel()
+
+	extracted := 0
+	attempted := 0
+	for i := range articles {
+		article := &articles[i]
+		if seen[article.Link] {
+			continue
+		}
+		if attempted == maxExtractPerFetch {
+			log.Printf("Extracted content for the first %d new articles from %s; the rest keep their descriptions", maxExtractPerFetch, source.Name)
+			break
+		}
+		if ctx.Err() != nil {
+			log.Printf("Content extraction time budget for %s used up after %d articles; the rest keep their descriptions", source.Name, attempted)
+			break
+		}
+		attempted++
+
+		content, err := ExtractArticleContent(ctx, app, article.Link)
+		if err != nil {
+			log.Printf("Could not extract content for %s: %v", article.Link, err)
+			continue
+		}
+
+		article.Content = content.Text
+		article.WordCount = content.WordCount
+		article.ImageURL = content.ImageURL
+		article.Byline = content.Byline
+		article.Keywords = extractKeywords(article.Title + " " + article.Description + " " + content.Text)
+		extracted++
+	}
+
+	if attempted > 0 {
+		log.Printf("Extracted content for %d of %d new articles from %s", extracted, attempted, source.Name)
+	}
+}
+
+// ExtractArticleContent downloads pageURL and pulls out its main text,
+// readability-style: paragraphs are scored by length and punctuation, their
+// scores flow up to the enclosing elements, and the best-scoring element
+// (adjusted for class names and link density) is taken as the article. It
+// gives up after extractPageTimeout or when ctx is done, whichever is first.
+func ExtractArticleContent(ctx context.Context, app *models.App, pageURL string) (ExtractedContent, error) {
+	var content ExtractedContent
+
+	// Never fall back to an unguarded client: the link is whatever the feed says
+	client := app.FeedClient
+	if client == nil {
+		client = NewFeedHTTPClient()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, pageURL, nil)
+	if err != nil {
+		return content, fmt.Errorf("invalid article URL: %v", err)
+	}
+	req.Header.Set("User-Agent", feedUserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	ctx, cancel := context.WithTimeout(ctx, extractPageTimeout)
+	defer cancel()
+
+	// Article pages often share a host with the feed; use the same politeness limits
+	release, err := feedHosts.acquire(ctx, req.URL.Hostname())
+	if err != nil {
+		return content, err
+	}
+	defer release()
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return content, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return content, &HTTPStatusError{StatusCode: resp.StatusCode}
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" && !strings.Contains(contentType, "html") {
+		return content, fmt.Errorf("page is %s, not HTML", contentType)
+	}
+
+	// Plenty of news sites still serve Latin-1 or Windows-1252
+	body, err := charset.NewReader(io.LimitReader(resp.Body, extractMaxPageSize), resp.Header.Get("Content-Type"))
+	if err != nil {
+		return content, err
+	}
+
+	doc, err := html.Parse(body)
+	if err != nil {
+		return content, err
+	}
+
+	// Resolve relative image URLs against wherever we were redirected to
+	return extractContent(doc, resp.Request.URL)
+}
+
+// extractContent finds the main text, lead image and byline of a parsed page
+func extractContent(doc *html.Node, pageURL *url.URL) (ExtractedContent, error) {
+	var content ExtractedContent
+
+	meta := pageMeta(doc)
+	for _, key := range []string{"og:image", "twitter:image", "twitter:image:src"} {
+		if meta[key] == "" {
+			continue
+		}
+		if image, err := pageURL.Parse(meta[key]); err == nil {
+			content.ImageURL = image.String()
+			break
+		}
+	}
+
+	// Read the byline before page furniture is stripped; it often lives in a header
+	content.Byline = findByline(doc, meta)
+
+	removeUnlikelyNodes(doc)
+
+	best := topCandidate(doc)
+	if best == nil {
+		return content, fmt.Errorf("no article text found")
+	}
+
+	var blocks []string
+	collectBlocks(best, &blocks)
+	content.Text = strings.Join(blocks, "\n\n")
+	if len(content.Text) < minExtractedLength {
+		return content, fmt.Errorf("no article text found")
+	}
+	content.WordCount = len(strings.Fields(content.Text))
+
+	return content, nil
+}
+
+// pageMeta maps the name or property of each <meta> tag to its content
+func pageMeta(doc *html.Node) map[string]string {
+	meta := map[string]string{}
+	walkNodes(doc, func(n *html.Node) bool {
+		if n.DataAtom == atom.Body {
+			return false
+		}
+		if n.DataAtom != atom.Meta {
+			return true
+		}
+		key := attr(n, "property")
+		if key == "" {
+			key = attr(n, "name")
+		}
+		key = strings.ToLower(key)
+		if key != "" && meta[key] == "" {
+			meta[key] = strings.TrimSpace(attr(n, "content"))
+		}
+		return true
+	})
+	return meta
+}
+
+// findByline returns the article's author from its metadata or markup
+func findByline(doc *html.Node, meta map[string]string) string {
+	for _, key := range []string{"author", "article:author", "parsely-author", "sailthru.author"} {
+		// article:author is often a profile URL rather than a name
+		if value := meta[key]; value != "" && !strings.HasPrefix(value, "http") {
+			return cleanByline(value)
+		}
+	}
+
+	var byline string
+	walkNodes(doc, func(n *html.Node) bool {
+		if byline != "" {
+			return false
+		}
+		if n.Type != html.ElementNode {
+			return true
+		}
+		// Skip scripts and the like, but not headers, where bylines often sit
+		if removedTags[n.DataAtom] && n.DataAtom != atom.Header {
+			return false
+		}
+		if attr(n, "rel") == "author" || attr(n, "itemprop") == "author" || bylineClass.MatchString(attr(n, "class")+" "+attr(n, "id")) {
+			if text := cleanByline(nodeText(n)); text != "" && len(text) <= maxBylineLength {
+				byline = text
+				return false
+			}
+		}
+		return true
+	})
+	return byline
+}
+
+// cleanByline trims a byline down to the names in it
+func cleanByline(byline string) string {
+	byline = strings.Join(strings.Fields(byline), " ")
+	if len(byline) > 3 && strings.EqualFold(byline[:3], "by ") {
+		byline = byline[3:]
+	}
+	return strings.TrimSpace(byline)
+}
+
+// removeUnlikelyNodes strips elements that never hold the article body
+func removeUnlikelyNodes(doc *html.Node) {
+	var remove []*html.Node
+	walkNodes(doc, func(n *html.Node) bool {
+		switch n.Type {
+		case html.CommentNode:
+			remove = append(remove, n)
+			return false
+		case html.ElementNode:
+		default:
+			return true
+		}
+		if n.DataAtom == atom.Html || n.DataAtom == atom.Body || n.DataAtom == atom.Article {
+			return true
+		}
+
+		classAndID := attr(n, "class") + " " + attr(n, "id")
+		if removedTags[n.DataAtom] || attr(n, "hidden") != "" || attr(n, "aria-hidden") == "true" ||
+			(unlikelyCandidate.MatchString(classAndID) && !maybeCandidate.MatchString(classAndID)) {
+			remove = append(remove, n)
+			return false
+		}
+		return true
+	})
+
+	for _, n := range remove {
+		if n.Parent != nil {
+			n.Parent.RemoveChild(n)
+		}
+	}
+}
+
+// topCandidate scores the parents of every paragraph and returns the element
+// most likely to be the article body
+func topCandidate(doc *html.Node) *html.Node {
+	scores := map[*html.Node]float64{}
+	var candidates []*html.Node
+
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+
+	walkNodes(doc, func(n *html.Node) bool {
+		if n.DataAtom != atom.P && n.DataAtom != atom.Pre {
+			return true
+		}
+		text := nodeText(n)
+		if len(text) < minParagraphLength {
+			return false
+		}
+
+		// One point per paragraph, one per comma and one per 100 characters (up to 3)
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text)/100), 3)
+		addScore(n.Parent, score)
+		if n.Parent != nil {
+			addScore(n.Parent.Parent, score/2)
+		}
+		return false
+	})
+
+	var best *html.Node
+	bestScore := 0.0
+	for _, n := range candidates {
+		score := scores[n] * (1 - linkDensity(n))
+		if best == nil || score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	return best
+}
+
+// initialScore weighs an element by its tag and class names before its paragraphs are counted
+func initialScore(n *html.Node) float64 {
+	score := 0.0
+	switch n.DataAtom {
+	case atom.Article:
+		score += 10
+	case atom.Div, atom.Main, atom.Section:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Ol, atom.Ul, atom.Dl, atom.Form:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+
+	for _, value := range []string{attr(n, "class"), attr(n, "id")} {
+		if value == "" {
+			continue
+		}
+		if negativeClass.MatchString(value) {
+			score -= 25
+		}
+		if positiveClass.MatchString(value) {
+			score += 25
+		}
+	}
+	return score
+}
+
+// collectBlocks appends the text of n's paragraphs, headings and list items
+// to blocks, skipping ones that are mostly links
+func collectBlocks(n *html.Node, blocks *[]string) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		if !textBlocks[child.DataAtom] {
+			collectBlocks(child, blocks)
+			continue
+		}
+		if text := nodeText(child); text != "" && linkDensity(child) < 0.5 {
+			*blocks = append(*blocks, text)
+		}
+	}
+}
+
+// linkDensity is the share of n's text that sits inside links
+func linkDensity(n *html.Node) float64 {
+	total := len(nodeText(n))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	walkNodes(n, func(child *html.Node) bool {
+		if child.DataAtom == atom.A {
+			linked += len(nodeText(child))
+			return false
+		}
+		return true
+	})
+	return float64(linked) / float64(total)
+}
+
+// nodeText is the text inside n with whitespace collapsed
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	walkNodes(n, func(child *html.Node) bool {
+		if child.Type == html.TextNode {
+			b.WriteString(child.Data)
+			b.WriteByte(' ')
+		}
+		return true
+	})
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// walkNodes calls fn for n and its descendants in document order, skipping
+// the children of nodes for which fn returns false
+func walkNodes(n *html.Node, fn func(*html.Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		walkNodes(child, fn)
+	}
+}
+
+// attr returns the value of n's attribute key, or "" if it has none
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	feedUserAgent    = "RSSToday/1.0 (+feed monitor)"
+	feedFetchTimeout = 30 * time.Second
+	maxFeedSize      = 20 << 20 // 20 MiB; anything larger isn't a news feed
+)
+
+// NewFeedHTTPClient builds the HTTP client used for all feed and article page
+// requests. Feeds and the article links in them come from users, so it only
+// connects to public addresses, checked at dial time and on every redirect.
+func NewFeedHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout:       feedFetchTimeout,
+		Transport:     newPublicTransport(),
+		CheckRedirect: checkPublicRedirect,
+	}
+}
+
+// FeedFetchResult describes one HTTP fetch of a source's feed
+type FeedFetchResult struct {
+	Feed         *gofeed.Feed // nil when NotModified or Unchanged
+	StatusCode   int
+	NotModified  bool          // Server answered 304
+	Unchanged    bool          // Server sent 200 but the body is byte-identical to last time
+	BytesFetched int64         // Body size we actually downloaded
+	BytesSaved   int64         // Estimated bytes we didn't have to download
+	UpdateHint   time.Duration // Publisher's <ttl>/sy:updatePeriod hint, when a feed was parsed
+
+	// Validators of a parsed feed. They aren't saved by fetchFeed: the caller
+	// saves them with SaveFeedValidators once the feed's articles are stored,
+	// so a failed save is retried on the next fetch instead of the feed
+	// looking unchanged.
+	Validators FeedValidators
+}
+
+// FeedValidators identify a version of a feed for conditional GETs
+type FeedValidators struct {
+	ETag         string
+	LastModified string
+	BodyHash     string // SHA-256 of the body, for servers that ignore validators
+}
+
+// fetchFeed downloads source's feed with If-None-Match/If-Modified-Since, parses
+// it only when it changed, and persists the new validators and byte counters
+func fetchFeed(app *models.App, source models.NewsSource) (FeedFetchResult, error) {
+	var result FeedFetchResult
+
+	client := app.FeedClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequest(http.MethodGet, source.RSSURL, nil)
+	if err != nil {
+		return result, fmt.Errorf("invalid feed URL: %v", err)
+	}
+	req.Header.Set("User-Agent", feedUserAgent)
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/feed+json, application/xml;q=0.9, */*;q=0.8")
+	if source.ETag != "" {
+		req.Header.Set("If-None-Match", source.ETag)
+	}
+	if source.LastModified != "" {
+		req.Header.Set("If-Modified-Since", source.LastModified)
+	}
+
+	// Be polite: one request per host at a time, spaced out, honoring Retry-After.
+	// Waiting for the host doesn't eat into the request's own timeout.
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), maxHostWait+feedFetchTimeout)
+	release, err := feedHosts.acquire(waitCtx, req.URL.Hostname())
+	cancelWait()
+	if err != nil {
+		return result, err
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), feedFetchTimeout)
+	defer cancel()
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		statusErr := &HTTPStatusError{StatusCode: resp.StatusCode}
+		if delay, ok := parseRetryAfter(resp.Header, time.Now()); ok {
+			statusErr.RetryAfter = delay
+			feedHosts.deferHost(req.URL.Hostname(), time.Now().Add(delay))
+		}
+		return result, statusErr
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		result.NotModified = true
+		result.BytesSaved = source.LastFetchBytes
+		recordFeedFetch(app, source, result, resp.Header, "")
+		return result, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return result, &HTTPStatusError{StatusCode: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize))
+	if err != nil {
+		return result, fmt.Errorf("error reading feed body: %v", err)
+	}
+	result.BytesFetched = int64(len(body))
+
+	// Plenty of servers ignore conditional headers; don't re-parse a body we've already seen
+	bodyHash := fmt.Sprintf("%x", sha256.Sum256(body))
+	if source.FeedHash != "" && bodyHash == source.FeedHash {
+		result.Unchanged = true
+		recordFeedFetch(app, source, result, resp.Header, bodyHash)
+		return result, nil
+	}
+
+	feed, err := app.Parser.Parse(bytes.NewReader(body))
+	if err != nil {
+		return result, err
+	}
+	result.Feed = feed
+	result.UpdateHint = feedUpdateHint(body, feed)
+	result.Validators = FeedValidators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		BodyHash:     bodyHash,
+	}
+
+	recordFeedFetch(app, source, result, resp.Header, "")
+	return result, nil
+}
+
+// recordFeedFetch updates bandwidth counters after a successful response. For
+// an unchanged body it also refreshes the validators (bodyHash is set); a
+// parsed feed's validators wait for SaveFeedValidators.
+func recordFeedFetch(app *models.App, source models.NewsSource, result FeedFetchResult, header http.Header, bodyHash string) {
+	updates := map[string]interface{}{
+		"bytes_fetched": gorm.Expr("bytes_fetched + ?", result.BytesFetched),
+		"bytes_saved":   gorm.Expr("bytes_saved + ?", result.BytesSaved),
+	}
+
+	if result.NotModified {
+		updates["not_modified_count"] = gorm.Expr("not_modified_count + 1")
+	} else {
+		updates["last_fetch_bytes"] = result.BytesFetched
+	}
+	if bodyHash != "" {
+		updates["etag"] = header.Get("ETag")
+		updates["last_modified"] = header.Get("Last-Modified")
+		updates["feed_hash"] = bodyHash
+	}
+
+	if err := app.DB.Model(&models.NewsSource{}).Where("id = ?", source.ID).UpdateColumns(updates).Error; err != nil {
+		// Not fatal: worst case we download the full feed again next time
+		log.Printf("Error saving fetch metadata for %s: %v", source.Name, err)
+	}
+}
+
+// SaveFeedValidators stores the validators of a fetched feed, so the next
+// fetch can be conditional. Call it only once the feed's articles are saved.
+func SaveFeedValidators(app *models.App, source models.NewsSource, validators FeedValidators) {
+	if validators.BodyHash == "" {
+		return
+	}
+
+	err := app.DB.Model(&models.NewsSource{}).Where("id = ?", source.ID).UpdateColumns(map[string]interface{}{
+		"etag":          validators.ETag,
+		"last_modified": validators.LastModified,
+		"feed_hash":     validators.BodyHash,
+	}).Error
+	if err != nil {
+		// Not fatal: worst case we download and parse the full feed again next time
+		log.Printf("Error saving feed validators for %s: %v", source.Name, err)
+	}
+}
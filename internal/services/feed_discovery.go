@@ -0,0 +1,321 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"golang.org/x/net/html"
+)
+
+// FeedCandidate is a feed found while inspecting a website
+type FeedCandidate struct {
+	URL       string `json:"url"`
+	Title     string `json:"title"`
+	FeedType  string `json:"feed_type"`  // "rss", "atom" or "json"
+	ItemCount int    `json:"item_count"` // Items in the feed right now
+	FoundVia  string `json:"found_via"`  // "direct", "link_tag" or "common_path"
+	Score     int    `json:"score"`      // Higher is better
+
+	finalURL string // Where URL led after redirects; candidates ending up at the same feed are merged
+}
+
+// commonFeedPaths are tried when a page doesn't advertise its feeds
+var commonFeedPaths = []string{
+	"/feed",
+	"/feed/",
+	"/rss",
+	"/rss.xml",
+	"/atom.xml",
+	"/feed.xml",
+	"/index.xml",
+	"/feeds/posts/default",
+}
+
+// feedMIMETypes are the <link rel="alternate"> types we treat as feeds
+var feedMIMETypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+	"application/json":      true,
+	"application/xml":       true,
+	"text/xml":              true,
+}
+
+const (
+	discoveryTimeout     = 15 * time.Second
+	discoveryMaxPageSize = 2 << 20 // 2 MiB is plenty to find the <head>
+)
+
+// DiscoverFeeds looks for feeds published by the website at siteURL. It checks
+// whether siteURL is itself a feed, reads <link rel="alternate"> tags from the
+// page and probes common feed paths, then returns the candidates that parse,
+// best first.
+func DiscoverFeeds(app *models.App, siteURL string) ([]FeedCandidate, error) {
+	base, err := url.Parse(strings.TrimSpace(siteURL))
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %v", err)
+	}
+	if base.Scheme == "" {
+		// Editors often paste "example.com"
+		base, err = url.Parse("https://" + strings.TrimSpace(siteURL))
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL: %v", err)
+		}
+	}
+	// Fetches go through app.FeedClient, which refuses internal addresses
+	// anyway; checking first gives a clear error instead of no candidates
+	if err := CheckPublicURL(context.Background(), base.String()); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Discovering feeds for %s", base.String())
+
+	// Candidate URL -> how we found it; first discovery wins
+	found := map[string]string{base.String(): "direct"}
+	order := []string{base.String()}
+	add := func(u, via string) {
+		if _, ok := found[u]; !ok {
+			found[u] = via
+			order = append(order, u)
+		}
+	}
+
+	linkTitles := map[string]string{}
+	links, err := fetchAlternateLinks(app, base)
+	if err != nil {
+		log.Printf("Could not read page %s for feed links: %v", base.String(), err)
+	}
+	for _, link := range links {
+		add(link.href, "link_tag")
+		if link.title != "" {
+			linkTitles[link.href] = link.title
+		}
+	}
+
+	root := &url.URL{Scheme: base.Scheme, Host: base.Host}
+	for _, path := range commonFeedPaths {
+		add(root.ResolveReference(&url.URL{Path: path}).String(), "common_path")
+	}
+
+	// Probe every candidate concurrently; there are only a handful
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var candidates []FeedCandidate
+	for _, candidateURL := range order {
+		wg.Add(1)
+		go func(candidateURL string) {
+			defer wg.Done()
+
+			candidate, ok := probeFeed(app, candidateURL, found[candidateURL])
+			if !ok {
+				return
+			}
+			if candidate.Title == "" {
+				candidate.Title = linkTitles[candidateURL]
+			}
+			candidate.Score = scoreCandidate(candidate, base)
+
+			mu.Lock()
+			candidates = append(candidates, candidate)
+			mu.Unlock()
+		}(candidateURL)
+	}
+	wg.Wait()
+
+	candidates = dedupeCandidates(candidates)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].URL < candidates[j].URL
+	})
+
+	log.Printf("Discovered %d feeds for %s", len(candidates), base.String())
+	return candidates, nil
+}
+
+type alternateLink struct {
+	href  string
+	title string
+}
+
+// fetchAlternateLinks downloads the page and returns the absolute URLs of its feed <link> tags
+func fetchAlternateLinks(app *models.App, page *url.URL) ([]alternateLink, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, page.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", feedUserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := discoveryClient(app).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	// Resolve relative hrefs against wherever we were redirected to
+	pageURL := resp.Request.URL
+
+	var links []alternateLink
+	tokenizer := html.NewTokenizer(io.LimitReader(resp.Body, discoveryMaxPageSize))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			// io.EOF or a malformed document; either way we're done
+			return links, nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if token.Data == "body" {
+				return links, nil
+			}
+			if token.Data != "link" {
+				continue
+			}
+
+			attrs := map[string]string{}
+			for _, attr := range token.Attr {
+				attrs[strings.ToLower(attr.Key)] = strings.TrimSpace(attr.Val)
+			}
+
+			if !hasToken(attrs["rel"], "alternate") || !feedMIMETypes[strings.ToLower(attrs["type"])] || attrs["href"] == "" {
+				continue
+			}
+
+			href, err := pageURL.Parse(attrs["href"])
+			if err != nil {
+				continue
+			}
+			links = append(links, alternateLink{href: href.String(), title: attrs["title"]})
+		}
+	}
+}
+
+// probeFeed parses candidateURL as a feed, reporting false if it isn't one
+func probeFeed(app *models.App, candidateURL, foundVia string) (FeedCandidate, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, candidateURL, nil)
+	if err != nil {
+		return FeedCandidate{}, false
+	}
+	req.Header.Set("User-Agent", feedUserAgent)
+
+	resp, err := discoveryClient(app).Do(req)
+	if err != nil {
+		return FeedCandidate{}, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return FeedCandidate{}, false
+	}
+
+	feed, err := app.Parser.Parse(io.LimitReader(resp.Body, maxFeedSize))
+	if err != nil {
+		return FeedCandidate{}, false
+	}
+
+	return FeedCandidate{
+		URL:       candidateURL,
+		Title:     strings.TrimSpace(feed.Title),
+		FeedType:  feed.FeedType,
+		ItemCount: len(feed.Items),
+		FoundVia:  foundVia,
+		finalURL:  resp.Request.URL.String(),
+	}, true
+}
+
+// discoveryClient is the client discovery fetches with: the feed client, which
+// only connects to public addresses
+func discoveryClient(app *models.App) *http.Client {
+	if app.FeedClient != nil {
+		return app.FeedClient
+	}
+	return NewFeedHTTPClient()
+}
+
+// scoreCandidate ranks advertised feeds above guessed ones, prefers feeds on the
+// same host with items in them, and mildly penalizes comment feeds
+func scoreCandidate(candidate FeedCandidate, base *url.URL) int {
+	score := 0
+
+	switch candidate.FoundVia {
+	case "direct":
+		score += 100
+	case "link_tag":
+		score += 50
+	case "common_path":
+		score += 20
+	}
+
+	if candidate.ItemCount > 0 {
+		score += 20
+		if candidate.ItemCount > 30 {
+			score += 10
+		} else {
+			score += candidate.ItemCount / 3
+		}
+	}
+
+	if u, err := url.Parse(candidate.URL); err == nil && strings.EqualFold(u.Hostname(), base.Hostname()) {
+		score += 5
+	}
+
+	lowerURL := strings.ToLower(candidate.URL)
+	lowerTitle := strings.ToLower(candidate.Title)
+	if strings.Contains(lowerURL, "comment") || strings.Contains(lowerTitle, "comments") {
+		score -= 30
+	}
+
+	return score
+}
+
+// dedupeCandidates collapses URLs that redirected to the same feed (e.g. /feed and /feed/),
+// keeping the one found the most reliable way
+func dedupeCandidates(candidates []FeedCandidate) []FeedCandidate {
+	best := map[string]int{}
+	var result []FeedCandidate
+	for _, candidate := range candidates {
+		key := candidate.finalURL
+		if key == "" {
+			key = candidate.URL
+		}
+		if idx, ok := best[key]; ok {
+			if candidate.Score > result[idx].Score {
+				result[idx] = candidate
+			}
+			continue
+		}
+		best[key] = len(result)
+		result = append(result, candidate)
+	}
+	return result
+}
+
+// hasToken reports whether the space-separated attribute value contains token
+func hasToken(value, token string) bool {
+	for _, field := range strings.Fields(strings.ToLower(value)) {
+		if field == token {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FetchWorkers is how many feeds are fetched at once across all hosts. Override with FETCH_WORKERS.
+func FetchWorkers() int {
+	workers := envInt("FETCH_WORKERS", 8)
+	if workers < 1 {
+		return 1
+	}
+	return workers
+}
+
+// HostMinInterval is the minimum gap between two requests to the same host. Override with FETCH_HOST_MIN_INTERVAL.
+func HostMinInterval() time.Duration {
+	return envDuration("FETCH_HOST_MIN_INTERVAL", 2*time.Second)
+}
+
+// maxHostWait is the longest a worker will sit waiting for a host; beyond that the
+// source is deferred to a later cycle instead of tying up the worker
+const maxHostWait = 30 * time.Second
+
+// maxRetryAfter caps how long we honor a server's Retry-After
+const maxRetryAfter = 24 * time.Hour
+
+// HostDeferredError is returned when a host asked us to back off (Retry-After)
+// for longer than we're willing to wait within a cycle
+type HostDeferredError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *HostDeferredError) Error() string {
+	return fmt.Sprintf("host %s asked us to wait until %s", e.Host, e.Until.Format(time.RFC3339))
+}
+
+// hostLimiter serializes requests per host and spaces them out
+type hostLimiter struct {
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	slot        chan struct{} // One request to a host at a time
+	nextAllowed time.Time     // Earliest start for the next request
+}
+
+// feedHosts is shared by every feed fetch in the process
+var feedHosts = &hostLimiter{hosts: map[string]*hostState{}}
+
+func (l *hostLimiter) state(host string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, ok := l.hosts[host]
+	if !ok {
+		state = &hostState{slot: make(chan struct{}, 1)}
+		l.hosts[host] = state
+	}
+	return state
+}
+
+// acquire waits until a request to host may start. The returned release func must
+// be called when the request finishes; it starts the minimum spacing clock.
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	state := l.state(host)
+
+	select {
+	case state.slot <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	l.mu.Lock()
+	wait := time.Until(state.nextAllowed)
+	until := state.nextAllowed
+	l.mu.Unlock()
+
+	if wait > maxHostWait {
+		<-state.slot
+		return nil, &HostDeferredError{Host: host, Until: until}
+	}
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			<-state.slot
+			return nil, ctx.Err()
+		}
+	}
+
+	release := func() {
+		l.mu.Lock()
+		if next := time.Now().Add(HostMinInterval()); next.After(state.nextAllowed) {
+			state.nextAllowed = next
+		}
+		l.mu.Unlock()
+		<-state.slot
+	}
+	return release, nil
+}
+
+// deferHost blocks further requests to host until the given time
+func (l *hostLimiter) deferHost(host string, until time.Time) {
+	state := l.state(host)
+
+	l.mu.Lock()
+	if until.After(state.nextAllowed) {
+		state.nextAllowed = until
+	}
+	l.mu.Unlock()
+}
+
+// parseRetryAfter reads a Retry-After header given either as seconds or an HTTP date
+func parseRetryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		delay = date.Sub(now)
+	} else {
+		return 0, false
+	}
+
+	if delay < 0 {
+		delay = 0
+	}
+	if delay > maxRetryAfter {
+		delay = maxRetryAfter
+	}
+	return delay, true
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"sync"
@@ -9,75 +10,292 @@ import (
 	"github.com/mrrobotisreal/rss_today_api/internal/models"
 )
 
-func MonitorAllSources(app *models.App) error {
-	app.Mu.Lock()
-	app.LastRun = time.Now()
-	app.Mu.Unlock()
+// Monitoring run triggers
+const (
+	TriggerScheduled = "scheduled"
+	TriggerStartup   = "startup"
+	TriggerManual    = "manual"
+	TriggerRefresh   = "source_refresh"
+)
+
+// ErrCycleLocked is returned when another instance is already running a monitoring cycle
+var ErrCycleLocked = errors.New("a monitoring cycle is already running on another instance")
+
+// StartMonitoringRun records a run for every active source and processes it in
+// the background, returning the run as first saved so callers can poll it by ID
+func StartMonitoringRun(app *models.App, trigger string) (models.MonitoringRun, error) {
+	run, err := createRun(app, trigger)
+	if err != nil {
+		return models.MonitoringRun{}, err
+	}
+	started := *run
+
+	go func() {
+		if err := MonitorAllSources(app, run); err != nil {
+			log.Printf("Error in %s monitoring: %v", trigger, err)
+		}
+	}()
 
-	log.Println("🔍 STARTING RSS MONITORING CYCLE...")
+	return started, nil
+}
+
+// RefreshSource fetches, saves and checks alerts for a single source right now,
+// regardless of its schedule or whether it's active. It doesn't need the cycle
+// lock; the per-source lock keeps it from overlapping with a running cycle.
+func RefreshSource(app *models.App, source models.NewsSource) (*models.MonitoringRun, error) {
+	run, err := createRun(app, TriggerRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	monitorSources(app, []models.NewsSource{source}, run)
+	return run, nil
+}
+
+// StartSourceRefresh records a refresh run for source and processes it in the background
+func StartSourceRefresh(app *models.App, source models.NewsSource) (models.MonitoringRun, error) {
+	run, err := createRun(app, TriggerRefresh)
+	if err != nil {
+		return models.MonitoringRun{}, err
+	}
+	started := *run
+
+	go monitorSources(app, []models.NewsSource{source}, run)
+
+	return started, nil
+}
+
+// MonitorAllSources fetches every active source right away, ignoring their schedules,
+// and records the outcome on run
+func MonitorAllSources(app *models.App, run *models.MonitoringRun) error {
+	lock, err := lockCycle(app)
+	if err == ErrCycleLocked {
+		finishRun(app, run, "skipped", err)
+		return err
+	}
+	if err != nil {
+		finishRun(app, run, "failed", err)
+		return err
+	}
+	defer lock.Unlock()
 
-	// Step 1: Get all active news sources from database
 	var sources []models.NewsSource
 	if err := app.DB.Where("active = ?", true).Find(&sources).Error; err != nil {
-		return fmt.Errorf("error fetching sources: %v", err)
+		err = fmt.Errorf("error fetching sources: %v", err)
+		finishRun(app, run, "failed", err)
+		return err
+	}
+
+	monitorSources(app, sources, run)
+	return nil
+}
+
+// MonitorDueSources fetches only the active sources whose next poll time has
+// arrived. The scheduler calls this every tick, so each source is polled on
+// its own interval (and backoff, when failing). When several replicas run,
+// only the one holding the cycle lock does any work. A run is only recorded
+// when there was something due.
+func MonitorDueSources(app *models.App, trigger string) error {
+	lock, err := lockCycle(app)
+	if err == ErrCycleLocked {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	// Read the schedule only once we hold the lock, so we see what the last holder wrote
+	var sources []models.NewsSource
+	if err := app.DB.Where("active = ? AND (next_fetch_at IS NULL OR next_fetch_at <= ?)", true, time.Now()).Find(&sources).Error; err != nil {
+		return fmt.Errorf("error fetching due sources: %v", err)
 	}
 
+	if len(sources) == 0 {
+		return nil
+	}
+
+	run, err := createRun(app, trigger)
+	if err != nil {
+		return err
+	}
+
+	monitorSources(app, sources, run)
+	return nil
+}
+
+// lockCycle takes the cluster-wide monitoring cycle lock
+func lockCycle(app *models.App) (*AdvisoryLock, error) {
+	lock, err := TryAdvisoryLock(app, LockNamespaceCycle, 0)
+	if err != nil {
+		return nil, fmt.Errorf("error taking monitoring lock: %v", err)
+	}
+	if lock == nil {
+		log.Println("⏭️ Skipping monitoring cycle - another instance holds the lock")
+		return nil, ErrCycleLocked
+	}
+	return lock, nil
+}
+
+// sourceResult is what one worker reports back about one source
+type sourceResult struct {
+	source      models.NewsSource
+	parsed      int
+	newArticles []models.Article
+	err         error
+}
+
+func monitorSources(app *models.App, sources []models.NewsSource, run *models.MonitoringRun) {
+	app.Mu.Lock()
+	app.LastRun = time.Now()
+	app.Mu.Unlock()
+
+	log.Printf("🔍 STARTING RSS MONITORING CYCLE (run %d)...", run.ID)
 	log.Printf("Monitoring %d RSS sources", len(sources))
 
-	// Step 2: Process all sources concurrently using goroutines
+	run.SourcesAttempted = len(sources)
+
+	// Process sources with a bounded pool of workers; per-host spacing happens in fetchFeed
 	var allNewArticles []models.Article
 	var wg sync.WaitGroup
-	articlesChan := make(chan []models.Article, len(sources))
+	jobs := make(chan models.NewsSource)
+	results := make(chan sourceResult, len(sources))
 
-	for _, source := range sources {
+	workers := FetchWorkers()
+	if workers > len(sources) {
+		workers = len(sources)
+	}
+
+	for i := 0; i < workers; i++ {
 		wg.Add(1)
-		go func(src models.NewsSource) {
+		go func() {
 			defer wg.Done()
 
-			// Fetch RSS feed
-			articles, err := FetchRSSFeed(app, src)
-			if err != nil {
-				log.Printf("Error fetching RSS for %s: %v", src.Name, err)
-				return
+			for src := range jobs {
+				parsed, newArticles, err := processSource(app, src)
+				if err != nil {
+					log.Printf("Error processing %s: %v", src.Name, err)
+				}
+				results <- sourceResult{source: src, parsed: parsed, newArticles: newArticles, err: err}
 			}
-
-			// Save new articles to database
-			newArticles, err := SaveNewArticles(app, articles)
-			if err != nil {
-				log.Printf("Error saving articles for %s: %v", src.Name, err)
-				return
-			}
-
-			// Send new articles to channel if any found
-			if len(newArticles) > 0 {
-				articlesChan <- newArticles
-			}
-
-			// Be respectful - small delay between requests
-			time.Sleep(1 * time.Second)
-		}(source)
+		}()
 	}
 
-	// Step 3: Collect all new articles
+	// Hand out sources, then wait for the workers to drain
 	go func() {
+		for _, source := range sources {
+			jobs <- source
+		}
+		close(jobs)
 		wg.Wait()
-		close(articlesChan)
+		close(results)
 	}()
 
-	for articles := range articlesChan {
-		allNewArticles = append(allNewArticles, articles...)
+	// Collect all new articles and per-source outcomes
+	for result := range results {
+		run.ArticlesParsed += result.parsed
+		run.ArticlesNew += len(result.newArticles)
+		allNewArticles = append(allNewArticles, result.newArticles...)
+
+		if result.err != nil {
+			var deferredErr *HostDeferredError
+			skipped := errors.Is(result.err, ErrSourceLocked) || errors.As(result.err, &deferredErr)
+			if skipped {
+				run.SourcesSkipped++
+			} else {
+				run.SourcesFailed++
+			}
+
+			run.SourceErrors = append(run.SourceErrors, models.MonitoringRunSourceError{
+				RunID:      run.ID,
+				SourceID:   result.source.ID,
+				SourceName: result.source.Name,
+				Error:      result.err.Error(),
+				Skipped:    skipped,
+			})
+		}
 	}
 
-	// Step 4: Check alerts and send notifications
+	// Check alerts and send notifications
 	if len(allNewArticles) > 0 {
 		log.Printf("📊 Found %d new articles total", len(allNewArticles))
-		if err := CheckAlertsForNewArticles(app, allNewArticles); err != nil {
+		matched, err := CheckAlertsForNewArticles(app, allNewArticles)
+		if err != nil {
 			log.Printf("Error checking alerts: %v", err)
 		}
+		run.AlertsMatched = matched
 	} else {
 		log.Println("📰 No new articles found this cycle")
 	}
 
+	finishRun(app, run, "completed", nil)
 	log.Println("✅ RSS monitoring cycle completed")
-	return nil
+}
+
+// ErrSourceLocked is returned when another instance is already processing a source
+var ErrSourceLocked = errors.New("source is being processed by another instance")
+
+// processSource fetches one source and saves its new articles while holding
+// that source's lock, so no two instances ever process it at the same time.
+// It returns how many articles the feed contained and which of them were new.
+func processSource(app *models.App, source models.NewsSource) (int, []models.Article, error) {
+	lock, err := TryAdvisoryLock(app, LockNamespaceSource, int32(source.ID))
+	if err != nil {
+		return 0, nil, err
+	}
+	if lock == nil {
+		return 0, nil, ErrSourceLocked
+	}
+	defer lock.Unlock()
+
+	// Fetch RSS feed
+	articles, validators, err := FetchRSSFeed(app, source)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// Runs before saving so the body is used for keywords and alert matching;
+	// bounded by ExtractTimeBudget so it can't hold this worker for long
+	if source.ExtractContent {
+		ExtractNewArticleContent(app, source, articles)
+	}
+
+	// Save new articles to database
+	newArticles, err := SaveNewArticles(app, articles)
+	if err != nil {
+		// Validators stay as they were, so the next fetch gets the full feed
+		// and retries the articles that weren't saved
+		return len(articles), newArticles, fmt.Errorf("error saving articles: %v", err)
+	}
+	SaveFeedValidators(app, source, validators)
+
+	return len(articles), newArticles, nil
+}
+
+func createRun(app *models.App, trigger string) (*models.MonitoringRun, error) {
+	run := &models.MonitoringRun{
+		Trigger:    trigger,
+		Status:     "running",
+		InstanceID: InstanceID,
+		StartedAt:  time.Now(),
+	}
+	if err := app.DB.Create(run).Error; err != nil {
+		return nil, fmt.Errorf("error recording monitoring run: %v", err)
+	}
+	return run, nil
+}
+
+// finishRun stores the final status, counters and source errors of run
+func finishRun(app *models.App, run *models.MonitoringRun, status string, runErr error) {
+	now := time.Now()
+	run.Status = status
+	run.FinishedAt = &now
+	if runErr != nil {
+		run.Error = runErr.Error()
+	}
+
+	// Save writes the counters and inserts the SourceErrors rows along with them
+	if err := app.DB.Save(run).Error; err != nil {
+		log.Printf("Error saving monitoring run %d: %v", run.ID, err)
+	}
 }
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"time"
+
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"gorm.io/gorm"
+)
+
+// The inbox shows each match once, however many channels it went out on.
+// Every NotificationSent for the same alert, article, digest and spike is one inbox
+// item, represented by its lowest ID, and read/dismissed state is kept in
+// step across the group. Deliveries to alert webhooks aren't inbox items.
+
+// inboxGroupKey identifies the inbox item a NotificationSent belongs to
+const inboxGroupKey = "(alert_id, article_id, COALESCE(digest_id, 0), COALESCE(spike_id, 0))"
+
+// InboxFilter narrows a user's inbox listing
+type InboxFilter struct {
+	UserID           uint
+	AlertID          uint       // 0 for every alert
+	Since            *time.Time // Created at or after
+	Until            *time.Time // Created before
+	UnreadOnly       bool
+	IncludeDismissed bool
+	Before           *InboxCursor // Only items after this one in listing order
+	Limit            int
+}
+
+// InboxCursor is the position of an inbox item in listing order
+type InboxCursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+// InboxDelivery is how one channel delivery of an inbox item went
+type InboxDelivery struct {
+	Method string    `json:"method"`
+	Status string    `json:"status"`
+	Error  string    `json:"error,omitempty"`
+	SentAt time.Time `json:"sent_at"`
+}
+
+// InboxItem is one match in a user's notification inbox
+type InboxItem struct {
+	models.NotificationSent
+	Deliveries []InboxDelivery     `json:"deliveries"`
+	Article    *models.Article     `json:"article,omitempty"` // With its Source; nil for digests and spikes
+	Digest     *models.AlertDigest `json:"digest,omitempty"`
+	Spike      *models.SpikeEvent  `json:"spike,omitempty"`
+}
+
+// inboxScope selects the rows representing a user's inbox items
+func inboxScope(db *gorm.DB, userID uint) *gorm.DB {
+	primary := db.Model(&models.NotificationSent{}).
+		Select("MIN(id)").
+		Where("user_id = ? AND method <> ?", userID, MethodAlertWebhook).
+		Group("alert_id, article_id, digest_id, spike_id")
+
+	return db.Model(&models.NotificationSent{}).Where("id IN (?)", primary)
+}
+
+// ListInbox returns a page of inbox items, newest first, with their
+// deliveries, article and source, or digest
+func ListInbox(app *models.App, filter InboxFilter) ([]InboxItem, error) {
+	scope := inboxScope(app.DB, filter.UserID)
+	if filter.AlertID != 0 {
+		scope = scope.Where("alert_id = ?", filter.AlertID)
+	}
+	if filter.Since != nil {
+		scope = scope.Where("created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		scope = scope.Where("created_at < ?", *filter.Until)
+	}
+	if filter.UnreadOnly {
+		scope = scope.Where("read_at IS NULL")
+	}
+	if !filter.IncludeDismissed {
+		scope = scope.Where("dismissed_at IS NULL")
+	}
+	if filter.Before != nil {
+		scope = scope.Where("(created_at, id) < (?, ?)", filter.Before.CreatedAt, filter.Before.ID)
+	}
+
+	// created_at rather than sent_at, which moves on every delivery retry
+	var rows []models.NotificationSent
+	if err := scope.Order("created_at DESC, id DESC").Limit(filter.Limit).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	items := make([]InboxItem, len(rows))
+	if len(rows) == 0 {
+		return items, nil
+	}
+
+	var alertIDs, articleIDs, digestIDs, spikeIDs []uint
+	for i, row := range rows {
+		items[i] = InboxItem{NotificationSent: row, Deliveries: []InboxDelivery{}}
+		alertIDs = append(alertIDs, row.AlertID)
+		switch {
+		case row.DigestID != nil:
+			digestIDs = append(digestIDs, *row.DigestID)
+		case row.SpikeID != nil:
+			spikeIDs = append(spikeIDs, *row.SpikeID)
+		default:
+			articleIDs = append(articleIDs, row.ArticleID)
+		}
+	}
+
+	// Every channel delivery for the items on this page
+	var siblings []models.NotificationSent
+	err := app.DB.Where("user_id = ? AND method <> ? AND alert_id IN ?", filter.UserID, MethodAlertWebhook, alertIDs).
+		Where("(article_id IN ? OR digest_id IN ? OR spike_id IN ?)", append(articleIDs, 0), append(digestIDs, 0), append(spikeIDs, 0)).
+		Order("id").Find(&siblings).Error
+	if err != nil {
+		return nil, err
+	}
+
+	index := map[[4]uint]*InboxItem{}
+	for i := range items {
+		index[inboxKey(items[i].NotificationSent)] = &items[i]
+	}
+	for _, sibling := range siblings {
+		if item, ok := index[inboxKey(sibling)]; ok {
+			item.Deliveries = append(item.Deliveries, InboxDelivery{
+				Method: sibling.Method,
+				Status: sibling.Status,
+				Error:  sibling.Error,
+				SentAt: sibling.SentAt,
+			})
+		}
+	}
+
+	if len(articleIDs) > 0 {
+		var articles []models.Article
+		err := app.DB.Preload("Source", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).Where("id IN ?", articleIDs).Find(&articles).Error
+		if err != nil {
+			return nil, err
+		}
+
+		byID := make(map[uint]*models.Article, len(articles))
+		for i := range articles {
+			byID[articles[i].ID] = &articles[i]
+		}
+		for i := range items {
+			if items[i].DigestID == nil && items[i].SpikeID == nil {
+				items[i].Article = byID[items[i].ArticleID]
+			}
+		}
+	}
+
+	if len(digestIDs) > 0 {
+		var digests []models.AlertDigest
+		if err := app.DB.Where("id IN ?", digestIDs).Find(&digests).Error; err != nil {
+			return nil, err
+		}
+
+		byID := make(map[uint]*models.AlertDigest, len(digests))
+		for i := range digests {
+			byID[digests[i].ID] = &digests[i]
+		}
+		for i := range items {
+			if items[i].DigestID != nil {
+				items[i].Digest = byID[*items[i].DigestID]
+			}
+		}
+	}
+
+	if len(spikeIDs) > 0 {
+		var spikes []models.SpikeEvent
+		if err := app.DB.Where("id IN ?", spikeIDs).Find(&spikes).Error; err != nil {
+			return nil, err
+		}
+
+		byID := make(map[uint]*models.SpikeEvent, len(spikes))
+		for i := range spikes {
+			byID[spikes[i].ID] = &spikes[i]
+		}
+		for i := range items {
+			if items[i].SpikeID != nil {
+				items[i].Spike = byID[*items[i].SpikeID]
+			}
+		}
+	}
+
+	return items, nil
+}
+
+// inboxKey is the inbox group a NotificationSent belongs to, matching inboxGroupKey
+func inboxKey(n models.NotificationSent) [4]uint {
+	var digestID, spikeID uint
+	if n.DigestID != nil {
+		digestID = *n.DigestID
+	}
+	if n.SpikeID != nil {
+		spikeID = *n.SpikeID
+	}
+	return [4]uint{n.AlertID, n.ArticleID, digestID, spikeID}
+}
+
+// CountUnread returns how many inbox items the user hasn't read or dismissed
+func CountUnread(app *models.App, userID uint) (int64, error) {
+	var count int64
+	err := inboxScope(app.DB, userID).Where("read_at IS NULL AND dismissed_at IS NULL").Count(&count).Error
+	return count, err
+}
+
+// updateInboxItems sets column on every delivery of the user's inbox items with the given IDs,
+// returning how many inbox items were affected
+func updateInboxItems(app *models.App, userID uint, ids []uint, column string, value interface{}) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	groups := app.DB.Model(&models.NotificationSent{}).
+		Select("alert_id, article_id, COALESCE(digest_id, 0), COALESCE(spike_id, 0)").
+		Where("user_id = ? AND id IN ?", userID, ids)
+
+	update := app.DB.Model(&models.NotificationSent{}).
+		Where("user_id = ? AND method <> ?", userID, MethodAlertWebhook).
+		Where(inboxGroupKey+" IN (?)", groups)
+	if value != nil {
+		// Keep the time an item was first read or dismissed
+		update = update.Where(column + " IS NULL")
+	}
+
+	if err := update.UpdateColumn(column, value).Error; err != nil {
+		return 0, err
+	}
+
+	var affected int64
+	err := inboxScope(app.DB, userID).Where("id IN ?", ids).Count(&affected).Error
+	return affected, err
+}
+
+// MarkNotificationsRead marks the user's inbox items with the given IDs as read
+func MarkNotificationsRead(app *models.App, userID uint, ids []uint) (int64, error) {
+	return updateInboxItems(app, userID, ids, "read_at", time.Now())
+}
+
+// MarkNotificationsUnread clears the read state of the user's inbox items with the given IDs
+func MarkNotificationsUnread(app *models.App, userID uint, ids []uint) (int64, error) {
+	return updateInboxItems(app, userID, ids, "read_at", nil)
+}
+
+// DismissNotifications hides the user's inbox items with the given IDs
+func DismissNotifications(app *models.App, userID uint, ids []uint) (int64, error) {
+	return updateInboxItems(app, userID, ids, "dismissed_at", time.Now())
+}
+
+// MarkAllNotificationsRead marks every unread inbox item as read, optionally
+// only for one alert or for items created before a time. It returns how many
+// inbox items were marked.
+func MarkAllNotificationsRead(app *models.App, userID, alertID uint, before *time.Time) (int64, error) {
+	filter := func(db *gorm.DB) *gorm.DB {
+		db = db.Where("read_at IS NULL")
+		if alertID != 0 {
+			db = db.Where("alert_id = ?", alertID)
+		}
+		if before != nil {
+			db = db.Where("created_at < ?", *before)
+		}
+		return db
+	}
+
+	var affected int64
+	if err := inboxScope(app.DB, userID).Scopes(filter).Count(&affected).Error; err != nil {
+		return 0, err
+	}
+
+	err := app.DB.Model(&models.NotificationSent{}).
+		Where("user_id = ? AND method <> ?", userID, MethodAlertWebhook).
+		Scopes(filter).
+		UpdateColumn("read_at", time.Now()).Error
+	return affected, err
+}
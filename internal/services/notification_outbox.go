@@ -0,0 +1,410 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"github.com/mrrobotisreal/rss_today_api/internal/notify"
+	"gorm.io/gorm"
+)
+
+// Outbox statuses
+const (
+	OutboxPending = "pending"
+	OutboxDone    = "done"
+	OutboxDead    = "dead"
+)
+
+const (
+	// notificationTimeout bounds a single delivery attempt
+	notificationTimeout = 15 * time.Second
+	// outboxBatchSize is how many entries one dispatcher claims at a time
+	outboxBatchSize = 10
+	// outboxLease is how long a claimed entry is hidden from other dispatchers.
+	// It covers every delivery in a batch timing out, plus slack for loading
+	// what they refer to, so a lease can't expire while its batch is still
+	// being worked through and have another replica send the same entries.
+	outboxLease = outboxBatchSize*notificationTimeout + time.Minute
+	// Retry delays start at outboxBaseDelay and double per attempt up to outboxMaxDelay
+	outboxBaseDelay = 30 * time.Second
+	outboxMaxDelay  = 6 * time.Hour
+)
+
+// NotificationMaxAttempts is how many deliveries are tried before an entry is
+// dead-lettered. Override with NOTIFY_MAX_ATTEMPTS.
+func NotificationMaxAttempts() int {
+	attempts := envInt("NOTIFY_MAX_ATTEMPTS", 8)
+	if attempts < 1 {
+		return 1
+	}
+	return attempts
+}
+
+// EnqueueNotifications records a pending NotificationSent per matched article and
+// alert method, plus its outbox entry, in a single transaction. Digest alerts
+// queue their matches for SendDueDigests instead of notifying per article.
+// Articles the alert has already been notified about are skipped, so the
+// scheduler and a backfill can safely see the same article.
+func EnqueueNotifications(db *gorm.DB, alert models.UserAlert, articles []models.Article) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Serialise enqueues per alert so the duplicate check below can't race
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", LockNamespaceAlert, int32(alert.ID)).Error; err != nil {
+			return err
+		}
+
+		articles, err := unnotifiedArticles(tx, alert, articles)
+		if err != nil || len(articles) == 0 {
+			return err
+		}
+
+		digest := IsDigestMode(alert.DeliveryMode)
+		if digest {
+			if err := queueDigestItems(tx, alert, articles); err != nil {
+				return err
+			}
+		}
+
+		// Webhooks the user registered on this alert get every match as it happens
+		var webhooks []models.AlertWebhook
+		if err := tx.Where("alert_id = ? AND active = ?", alert.ID, true).Find(&webhooks).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, article := range articles {
+			var notifications []models.NotificationSent
+			if !digest {
+				for _, method := range alertMethods(alert) {
+					notifications = append(notifications, models.NotificationSent{Method: method})
+				}
+			}
+			for _, webhook := range webhooks {
+				webhookID := webhook.ID
+				notifications = append(notifications, models.NotificationSent{Method: MethodAlertWebhook, WebhookID: &webhookID})
+			}
+
+			for _, notification := range notifications {
+				notification.UserID = alert.UserID
+				notification.ArticleID = article.ID
+				notification.AlertID = alert.ID
+
+				if err := queueNotification(tx, &notification, now); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// queueNotification saves notification as pending together with its outbox entry
+func queueNotification(tx *gorm.DB, notification *models.NotificationSent, now time.Time) error {
+	notification.Status = "pending"
+	notification.SentAt = now
+
+	if err := tx.Create(notification).Error; err != nil {
+		return err
+	}
+
+	entry := models.NotificationOutbox{
+		NotificationID: notification.ID,
+		Status:         OutboxPending,
+		NextAttemptAt:  now,
+	}
+	return tx.Create(&entry).Error
+}
+
+// unnotifiedArticles drops the articles alert has already matched, whether
+// they were notified individually or queued for a digest
+func unnotifiedArticles(tx *gorm.DB, alert models.UserAlert, articles []models.Article) ([]models.Article, error) {
+	ids := make([]uint, 0, len(articles))
+	for _, article := range articles {
+		ids = append(ids, article.ID)
+	}
+
+	var seen []uint
+	err := tx.Raw(`
+		SELECT article_id FROM notification_sents WHERE alert_id = ? AND article_id IN ?
+		UNION
+		SELECT article_id FROM digest_items WHERE alert_id = ? AND article_id IN ?`,
+		alert.ID, ids, alert.ID, ids).Scan(&seen).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(seen) == 0 {
+		return articles, nil
+	}
+
+	skip := make(map[uint]bool, len(seen))
+	for _, id := range seen {
+		skip[id] = true
+	}
+
+	fresh := make([]models.Article, 0, len(articles))
+	for _, article := range articles {
+		if !skip[article.ID] {
+			fresh = append(fresh, article)
+		}
+	}
+	return fresh, nil
+}
+
+// DispatchNotifications delivers due outbox entries until none are left,
+// returning how many it processed. Entries are claimed with SKIP LOCKED and a
+// lease, so several dispatchers (and replicas) can run at once safely.
+func DispatchNotifications(app *models.App) (int, error) {
+	processed := 0
+	recipients := map[uint]notify.Recipient{}
+
+	for {
+		entries, err := claimOutboxEntries(app)
+		if err != nil {
+			return processed, err
+		}
+		if len(entries) == 0 {
+			return processed, nil
+		}
+
+		for _, entry := range entries {
+			dispatchEntry(app, entry, recipients)
+			processed++
+		}
+	}
+}
+
+// claimOutboxEntries leases a batch of due pending entries to this dispatcher
+func claimOutboxEntries(app *models.App) ([]models.NotificationOutbox, error) {
+	now := time.Now()
+
+	var ids []uint
+	err := app.DB.Raw(`
+		UPDATE notification_outboxes SET next_attempt_at = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM notification_outboxes
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id`,
+		now.Add(outboxLease), now, OutboxPending, now, outboxBatchSize).Scan(&ids).Error
+	if err != nil {
+		return nil, fmt.Errorf("error claiming outbox entries: %v", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var entries []models.NotificationOutbox
+	if err := app.DB.Preload("Notification").Where("id IN ?", ids).Order("id").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// dispatchEntry attempts one delivery and records the outcome on both the
+// outbox entry and its NotificationSent
+func dispatchEntry(app *models.App, entry models.NotificationOutbox, recipients map[uint]notify.Recipient) {
+	notification := entry.Notification
+	now := time.Now()
+	attempts := entry.Attempts + 1
+
+	status, err := deliverNotification(app, notification, recipients)
+
+	entryUpdates := map[string]interface{}{"attempts": attempts}
+	notificationUpdates := map[string]interface{}{"sent_at": now, "error": ""}
+	if err != nil {
+		entryUpdates["last_error"] = err.Error()
+		notificationUpdates["error"] = err.Error()
+	}
+
+	switch {
+	case status == notify.StatusSent || status == notify.StatusSkipped:
+		entryUpdates["status"] = OutboxDone
+		notificationUpdates["status"] = status
+	case attempts >= NotificationMaxAttempts():
+		entryUpdates["status"] = OutboxDead
+		notificationUpdates["status"] = notify.StatusFailed
+		log.Printf("☠️ Notification %d dead-lettered after %d attempts: %v", notification.ID, attempts, err)
+	default:
+		// Leave it pending and try again later
+		entryUpdates["next_attempt_at"] = now.Add(outboxRetryDelay(attempts))
+	}
+
+	err = app.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.NotificationOutbox{}).Where("id = ?", entry.ID).Updates(entryUpdates).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.NotificationSent{}).Where("id = ?", notification.ID).Updates(notificationUpdates).Error
+	})
+	if err != nil {
+		log.Printf("Error recording delivery of notification %d: %v", notification.ID, err)
+	}
+}
+
+// outboxRetryDelay is the wait before retry number attempts+1
+func outboxRetryDelay(attempts int) time.Duration {
+	delay := outboxBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= outboxMaxDelay {
+			return outboxMaxDelay
+		}
+	}
+	return delay
+}
+
+// deliverNotification builds the message for notification and sends it over its method
+func deliverNotification(app *models.App, notification models.NotificationSent, recipients map[uint]notify.Recipient) (string, error) {
+	var alert models.UserAlert
+	if err := app.DB.First(&alert, notification.AlertID).Error; err != nil {
+		return lookupStatus(err, "alert", notification.AlertID)
+	}
+
+	if notification.DigestID != nil {
+		return deliverDigest(app, notification, alert, recipients)
+	}
+	if notification.SpikeID != nil {
+		return deliverSpike(app, notification, alert, recipients)
+	}
+
+	var article models.Article
+	if err := app.DB.Preload("Source", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).First(&article, notification.ArticleID).Error; err != nil {
+		return lookupStatus(err, "article", notification.ArticleID)
+	}
+
+	if notification.Method == MethodAlertWebhook {
+		return deliverToAlertWebhook(app, notification, alert, article)
+	}
+
+	recipient, ok := recipients[notification.UserID]
+	if !ok {
+		recipient = loadRecipient(app, notification.UserID)
+		recipients[notification.UserID] = recipient
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+	defer cancel()
+
+	return app.Notifiers.Deliver(ctx, notification.Method, recipient, buildAlertMessage(alert, article, article.Source))
+}
+
+// deliverDigest renders the AlertDigest behind notification and sends it over its method
+func deliverDigest(app *models.App, notification models.NotificationSent, alert models.UserAlert, recipients map[uint]notify.Recipient) (string, error) {
+	var digest models.AlertDigest
+	if err := app.DB.First(&digest, *notification.DigestID).Error; err != nil {
+		return lookupStatus(err, "digest", *notification.DigestID)
+	}
+
+	articles, err := loadDigestArticles(app, digest.ID)
+	if err != nil {
+		return notify.StatusFailed, err
+	}
+	if len(articles) == 0 {
+		return notify.StatusSkipped, fmt.Errorf("articles in digest %d no longer exist", digest.ID)
+	}
+
+	var user models.User
+	if err := app.DB.First(&user, notification.UserID).Error; err != nil {
+		return lookupStatus(err, "user", notification.UserID)
+	}
+
+	recipient, ok := recipients[notification.UserID]
+	if !ok {
+		recipient = loadRecipient(app, notification.UserID)
+		recipients[notification.UserID] = recipient
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+	defer cancel()
+
+	return app.Notifiers.Deliver(ctx, notification.Method, recipient, buildDigestMessage(alert, digest, articles, UserLocation(user)))
+}
+
+// deliverSpike renders the SpikeEvent behind notification and sends it over its method
+func deliverSpike(app *models.App, notification models.NotificationSent, alert models.UserAlert, recipients map[uint]notify.Recipient) (string, error) {
+	var event models.SpikeEvent
+	if err := app.DB.First(&event, *notification.SpikeID).Error; err != nil {
+		return lookupStatus(err, "spike", *notification.SpikeID)
+	}
+
+	articles, err := loadSpikeArticles(app, alert, event)
+	if err != nil {
+		return notify.StatusFailed, err
+	}
+
+	if notification.Method == MethodAlertWebhook {
+		return deliverSpikeToAlertWebhook(app, notification, alert, event, articles)
+	}
+
+	var user models.User
+	if err := app.DB.First(&user, notification.UserID).Error; err != nil {
+		return lookupStatus(err, "user", notification.UserID)
+	}
+
+	recipient, ok := recipients[notification.UserID]
+	if !ok {
+		recipient = loadRecipient(app, notification.UserID)
+		recipients[notification.UserID] = recipient
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+	defer cancel()
+
+	return app.Notifiers.Deliver(ctx, notification.Method, recipient, buildSpikeMessage(alert, event, articles, UserLocation(user)))
+}
+
+// loadRecipient gathers the addresses a user can be notified at
+func loadRecipient(app *models.App, userID uint) notify.Recipient {
+	recipient := notify.Recipient{UserID: userID}
+
+	var user models.User
+	if err := app.DB.First(&user, userID).Error; err != nil {
+		log.Printf("Error loading user %d for notifications: %v", userID, err)
+	} else {
+		recipient.Email = user.Email
+	}
+
+	if err := app.DB.Model(&models.DeviceToken{}).Where("user_id = ?", userID).Pluck("token", &recipient.DeviceTokens).Error; err != nil {
+		log.Printf("Error loading devices for user %d: %v", userID, err)
+	}
+
+	return recipient
+}
+
+// ReplayDeadLetter puts a dead-lettered entry back in the queue with a fresh set of attempts
+func ReplayDeadLetter(app *models.App, id uint) error {
+	return app.DB.Transaction(func(tx *gorm.DB) error {
+		var entry models.NotificationOutbox
+		if err := tx.Where("id = ? AND status = ?", id, OutboxDead).First(&entry).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&entry).Updates(map[string]interface{}{
+			"status":          OutboxPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.NotificationSent{}).Where("id = ?", entry.NotificationID).Update("status", "pending").Error
+	})
+}
+
+// lookupStatus classifies a failed lookup of something a notification refers
+// to. Only a missing row means the notification can never be sent; anything
+// else (a timeout, a dropped connection) is a failure the outbox retries.
+func lookupStatus(err error, what string, id uint) (string, error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return notify.StatusSkipped, fmt.Errorf("%s %d no longer exists", what, id)
+	}
+	return notify.StatusFailed, fmt.Errorf("error loading %s %d: %v", what, id, err)
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"github.com/mrrobotisreal/rss_today_api/internal/notify"
+)
+
+// InitNotifiers registers every notification channel that has configuration:
+//
+//   - email:   SMTP_HOST, SMTP_PORT (default 587), SMTP_USERNAME, SMTP_PASSWORD, SMTP_FROM
+//   - webhook: NOTIFY_WEBHOOK_URL
+//   - push:    enabled whenever Firebase Messaging initialized
+func InitNotifiers(app *models.App) {
+	registry := notify.NewRegistry()
+
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		from := os.Getenv("SMTP_FROM")
+		if from == "" {
+			from = os.Getenv("SMTP_USERNAME")
+		}
+
+		registry.Register(&notify.EmailNotifier{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		})
+	}
+
+	if webhookURL := os.Getenv("NOTIFY_WEBHOOK_URL"); webhookURL != "" {
+		registry.Register(&notify.WebhookNotifier{
+			URL:    webhookURL,
+			Client: &http.Client{Timeout: 10 * time.Second},
+		})
+	}
+
+	if app.Messaging != nil {
+		registry.Register(&notify.PushNotifier{
+			Client: app.Messaging,
+			OnInvalidTokens: func(tokens []string) {
+				if err := app.DB.Where("token IN ?", tokens).Delete(&models.DeviceToken{}).Error; err != nil {
+					log.Printf("Error removing unregistered device tokens: %v", err)
+				}
+			},
+		})
+	}
+
+	app.Notifiers = registry
+	log.Printf("Notification channels configured: %v", registry.Channels())
+}
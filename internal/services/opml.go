@@ -0,0 +1,159 @@
+package services
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"golang.org/x/net/html/charset"
+)
+
+// OPML is an OPML 2.0 document
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    OPMLHead `xml:"head"`
+	Body    OPMLBody `xml:"body"`
+}
+
+type OPMLHead struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type OPMLBody struct {
+	Outlines []OPMLOutline `xml:"outline"`
+}
+
+// OPMLOutline is either a feed (XMLURL set) or a category holding more outlines
+type OPMLOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	Outlines []OPMLOutline `xml:"outline"`
+}
+
+// OPMLFeed is a single feed pulled out of an OPML document
+type OPMLFeed struct {
+	Title    string `json:"title"`
+	XMLURL   string `json:"xml_url"`
+	HTMLURL  string `json:"html_url,omitempty"`
+	Category string `json:"category,omitempty"` // Nested categories joined with "/"
+}
+
+// ParseOPML reads an OPML document and flattens it into its feeds, keeping
+// the path of enclosing category outlines on each one
+func ParseOPML(r io.Reader) ([]OPMLFeed, error) {
+	var doc OPML
+	decoder := xml.NewDecoder(r)
+	// encoding/xml only reads UTF-8, and plenty of exporters declare ISO-8859-1 or windows-1252
+	decoder.CharsetReader = charset.NewReaderLabel
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid OPML: %v", err)
+	}
+
+	var feeds []OPMLFeed
+	var walk func(outlines []OPMLOutline, path []string)
+	walk = func(outlines []OPMLOutline, path []string) {
+		for _, outline := range outlines {
+			name := strings.TrimSpace(outline.Title)
+			if name == "" {
+				name = strings.TrimSpace(outline.Text)
+			}
+
+			if outline.XMLURL != "" {
+				feeds = append(feeds, OPMLFeed{
+					Title:    name,
+					XMLURL:   strings.TrimSpace(outline.XMLURL),
+					HTMLURL:  strings.TrimSpace(outline.HTMLURL),
+					Category: strings.Join(path, "/"),
+				})
+			}
+
+			if len(outline.Outlines) > 0 {
+				childPath := path
+				if outline.XMLURL == "" && name != "" {
+					childPath = append(append([]string{}, path...), name)
+				}
+				walk(outline.Outlines, childPath)
+			}
+		}
+	}
+	walk(doc.Body.Outlines, nil)
+
+	return feeds, nil
+}
+
+// BuildOPML renders sources as an OPML 2.0 document, nesting them under their categories
+func BuildOPML(title string, sources []models.NewsSource) ([]byte, error) {
+	root := &categoryNode{children: map[string]*categoryNode{}}
+	for _, source := range sources {
+		node := root
+		for _, part := range strings.Split(source.Category, "/") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			child, ok := node.children[part]
+			if !ok {
+				child = &categoryNode{name: part, children: map[string]*categoryNode{}}
+				node.children[part] = child
+			}
+			node = child
+		}
+		node.feeds = append(node.feeds, OPMLOutline{
+			Text:    source.Name,
+			Title:   source.Name,
+			Type:    "rss",
+			XMLURL:  source.RSSURL,
+			HTMLURL: source.URL,
+		})
+	}
+
+	doc := OPML{
+		Version: "2.0",
+		Head: OPMLHead{
+			Title:       title,
+			DateCreated: time.Now().UTC().Format(time.RFC1123Z),
+		},
+		Body: OPMLBody{Outlines: root.outlines()},
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+type categoryNode struct {
+	name     string
+	children map[string]*categoryNode
+	feeds    []OPMLOutline
+}
+
+// outlines returns category outlines (alphabetically) followed by this node's feeds
+func (n *categoryNode) outlines() []OPMLOutline {
+	names := make([]string, 0, len(n.children))
+	for name := range n.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var result []OPMLOutline
+	for _, name := range names {
+		child := n.children[name]
+		result = append(result, OPMLOutline{
+			Text:     child.name,
+			Title:    child.name,
+			Outlines: child.outlines(),
+		})
+	}
+	return append(result, n.feeds...)
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"bytes"
+	"encoding/xml"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+)
+
+const (
+	// MinPollInterval and MaxPollInterval bound the interval a source can be configured with
+	MinPollInterval = 1 * time.Minute
+	MaxPollInterval = 7 * 24 * time.Hour
+
+	// Adaptive polling never goes faster or slower than this
+	minAdaptiveInterval = 2 * time.Minute
+	maxAdaptiveInterval = 24 * time.Hour
+
+	// How many recent articles to look at when learning a source's publish cadence
+	cadenceSampleSize = 20
+)
+
+// DefaultPollInterval is used for sources without their own interval. Override with DEFAULT_POLL_INTERVAL (e.g. "5m").
+func DefaultPollInterval() time.Duration {
+	return envDuration("DEFAULT_POLL_INTERVAL", 10*time.Minute)
+}
+
+// PollInterval returns how long to wait between successful fetches of source
+func PollInterval(source models.NewsSource) time.Duration {
+	interval := DefaultPollInterval()
+	if source.PollIntervalMinutes > 0 {
+		interval = time.Duration(source.PollIntervalMinutes) * time.Minute
+	}
+
+	if source.AdaptivePolling {
+		if source.AdaptiveIntervalMinutes > 0 {
+			interval = time.Duration(source.AdaptiveIntervalMinutes) * time.Minute
+		}
+		// The publisher told us how often it's worth checking; don't go faster
+		if hint := time.Duration(source.UpdateHintMinutes) * time.Minute; hint > interval {
+			interval = hint
+		}
+	}
+
+	if interval < MinPollInterval {
+		interval = MinPollInterval
+	}
+	if interval > MaxPollInterval {
+		interval = MaxPollInterval
+	}
+	return interval
+}
+
+// learnPollInterval estimates a good interval from the gaps between the source's
+// recent articles, polling about twice per typical gap. It returns 0 when there
+// isn't enough history to tell.
+func learnPollInterval(app *models.App, source models.NewsSource) time.Duration {
+	var pubDates []time.Time
+	err := app.DB.Model(&models.Article{}).
+		Where("source_id = ?", source.ID).
+		Order("pub_date DESC").
+		Limit(cadenceSampleSize).
+		Pluck("pub_date", &pubDates).Error
+	if err != nil || len(pubDates) < 3 {
+		return 0
+	}
+
+	var gaps []time.Duration
+	for i := 1; i < len(pubDates); i++ {
+		if gap := pubDates[i-1].Sub(pubDates[i]); gap > 0 {
+			gaps = append(gaps, gap)
+		}
+	}
+	if len(gaps) == 0 {
+		return 0
+	}
+
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+	interval := gaps[len(gaps)/2] / 2
+
+	if interval < minAdaptiveInterval {
+		interval = minAdaptiveInterval
+	}
+	if interval > maxAdaptiveInterval {
+		interval = maxAdaptiveInterval
+	}
+	return interval
+}
+
+// feedUpdateHint reads the publisher's refresh hints: RSS <ttl> (minutes) and
+// the syndication module's sy:updatePeriod/sy:updateFrequency. It returns the
+// longer of the two, or 0 if the feed gives none.
+func feedUpdateHint(body []byte, feed *gofeed.Feed) time.Duration {
+	var hint time.Duration
+
+	// gofeed's universal Feed drops <ttl>, so read it from the raw document
+	var rss struct {
+		Channel struct {
+			TTL string `xml:"ttl"`
+		} `xml:"channel"`
+	}
+	if feed.FeedType == "rss" && xml.Unmarshal(bytes.TrimSpace(body), &rss) == nil {
+		if minutes, err := strconv.Atoi(strings.TrimSpace(rss.Channel.TTL)); err == nil && minutes > 0 {
+			hint = time.Duration(minutes) * time.Minute
+		}
+	}
+
+	if sy, ok := feed.Extensions["sy"]; ok {
+		var period time.Duration
+		if values := sy["updatePeriod"]; len(values) > 0 {
+			switch strings.ToLower(strings.TrimSpace(values[0].Value)) {
+			case "hourly":
+				period = time.Hour
+			case "daily":
+				period = 24 * time.Hour
+			case "weekly":
+				period = 7 * 24 * time.Hour
+			case "monthly":
+				period = 30 * 24 * time.Hour
+			case "yearly":
+				period = 365 * 24 * time.Hour
+			}
+		}
+
+		frequency := 1
+		if values := sy["updateFrequency"]; len(values) > 0 {
+			if parsed, err := strconv.Atoi(strings.TrimSpace(values[0].Value)); err == nil && parsed > 0 {
+				frequency = parsed
+			}
+		}
+
+		if period > 0 {
+			if syHint := period / time.Duration(frequency); syHint > hint {
+				hint = syHint
+			}
+		}
+	}
+
+	return hint
+}
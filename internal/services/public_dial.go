@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ErrNonPublicAddress is returned for URLs that resolve to loopback, private,
+// link-local or otherwise internal addresses. Users choose webhook, feed and
+// article URLs, so requests to them must never reach our own network.
+var ErrNonPublicAddress = errors.New("address is not publicly routable")
+
+// maxRedirects matches net/http's default redirect limit
+const maxRedirects = 10
+
+// carrierGradeNAT is 100.64.0.0/10, which net.IP.IsPrivate doesn't cover
+var carrierGradeNAT = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPublicIP reports whether ip is a globally routable unicast address
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || carrierGradeNAT.Contains(ip))
+}
+
+// resolvePublic looks up host and returns its addresses, failing if any of
+// them isn't public so a name can't mix a public and an internal record
+func resolvePublic(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		if !isPublicIP(ip) {
+			return nil, fmt.Errorf("%s: %w", host, ErrNonPublicAddress)
+		}
+		return []net.IP{ip}, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		if !isPublicIP(addr.IP) {
+			return nil, fmt.Errorf("%s: %w", host, ErrNonPublicAddress)
+		}
+		ips = append(ips, addr.IP)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("%s has no addresses", host)
+	}
+	return ips, nil
+}
+
+// CheckPublicURL checks that rawURL is an absolute http or https URL whose
+// host resolves only to public addresses
+func CheckPublicURL(ctx context.Context, rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return fmt.Errorf("URL must be an absolute http or https URL")
+	}
+	_, err = resolvePublic(ctx, parsed.Hostname())
+	return err
+}
+
+// publicDialContext dials only public addresses. It connects to the address
+// it checked rather than resolving again, so DNS rebinding can't slip an
+// internal address in between the check and the connection.
+func publicDialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := resolvePublic(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var lastErr error
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
+
+// checkPublicRedirect is an http.Client CheckRedirect that refuses redirects
+// to non-http schemes or internal hosts
+func checkPublicRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	return CheckPublicURL(req.Context(), req.URL.String())
+}
+
+// newPublicTransport is an HTTP transport that only connects to public addresses
+func newPublicTransport() *http.Transport {
+	return &http.Transport{
+		// No proxy: it would make the connection for us, bypassing the address check
+		DialContext: publicDialContext(&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}),
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   4,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 20 * time.Second,
+	}
+}
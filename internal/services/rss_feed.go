@@ -2,6 +2,7 @@ package services
 
 import (
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"html"
 	"log"
@@ -13,13 +14,34 @@ import (
 	"github.com/mrrobotisreal/rss_today_api/internal/models"
 )
 
-func FetchRSSFeed(app *models.App, source models.NewsSource) ([]models.Article, error) {
+// FetchRSSFeed fetches and parses source's feed. The returned validators are
+// for SaveFeedValidators once the articles have been stored.
+func FetchRSSFeed(app *models.App, source models.NewsSource) ([]models.Article, FeedValidators, error) {
 	log.Printf("Fetching RSS feed for %s", source.Name)
 
-	feed, err := app.Parser.ParseURL(source.RSSURL)
+	result, err := fetchFeed(app, source)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing RSS feed for %s: %v", source.Name, err)
+		// The host asked us to slow down before we even sent a request; that's not the feed's fault
+		var deferredErr *HostDeferredError
+		if errors.As(err, &deferredErr) {
+			DeferSource(app, source, deferredErr.Until)
+			return nil, FeedValidators{}, fmt.Errorf("skipping %s for now: %w", source.Name, err)
+		}
+
+		RecordFetchFailure(app, source, result.StatusCode, err)
+		return nil, FeedValidators{}, fmt.Errorf("error parsing RSS feed for %s: %w", source.Name, err)
+	}
+	RecordFetchSuccess(app, source, result)
+
+	if result.NotModified {
+		log.Printf("%s not modified since last fetch (saved ~%d bytes)", source.Name, result.BytesSaved)
+		return nil, FeedValidators{}, nil
 	}
+	if result.Unchanged {
+		log.Printf("%s feed body unchanged since last fetch, skipping parse", source.Name)
+		return nil, FeedValidators{}, nil
+	}
+	feed := result.Feed
 
 	// Initialize Google News decoder for this source if needed
 	var decoder *GoogleNewsDecoder
@@ -73,7 +95,7 @@ func FetchRSSFeed(app *models.App, source models.NewsSource) ([]models.Article,
 	}
 
 	log.Printf("Parsed %d articles from %s", len(articles), source.Name)
-	return articles, nil
+	return articles, result.Validators, nil
 }
 
 // CleanContent removes HTML tags, decodes HTML entities, and cleans up text
@@ -159,9 +181,7 @@ func extractKeywords(text string) []string {
 	var keywords []string
 
 	for _, word := range words {
-		// Remove punctuation and keep words longer than 3 characters
-		cleaned := strings.Trim(word, ".,!?:;\"'()[]{}*-_+=<>/\\|")
-		if len(cleaned) > 3 && !isStopWord(cleaned) {
+		if cleaned := indexableKeyword(word); cleaned != "" {
 			keywords = append(keywords, cleaned)
 		}
 	}
@@ -175,6 +195,17 @@ func extractKeywords(text string) []string {
 	return uniqueKeywords
 }
 
+// indexableKeyword returns word as extractKeywords stores it: lowercased,
+// without surrounding punctuation. It returns "" for words extractKeywords
+// drops, those of 3 characters or fewer and stop words.
+func indexableKeyword(word string) string {
+	cleaned := strings.Trim(strings.ToLower(word), ".,!?:;\"'()[]{}*-_+=<>/\\|")
+	if len(cleaned) > 3 && !isStopWord(cleaned) {
+		return cleaned
+	}
+	return ""
+}
+
 func isStopWord(word string) bool {
 	stopWords := map[string]bool{
 		"the": true, "and": true, "for": true, "are": true, "but": true,
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"gorm.io/gorm"
+)
+
+// HTTPStatusError is returned when a feed server answers with a non-success status
+type HTTPStatusError struct {
+	StatusCode int
+	RetryAfter time.Duration // From the Retry-After header on 429/503, if any
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status %d", e.StatusCode)
+}
+
+// maxBackoff caps how long a failing source waits between attempts
+const maxBackoff = 24 * time.Hour
+
+// SourceFailureThreshold is the number of consecutive failures after which a
+// source is deactivated automatically. Set SOURCE_FAILURE_THRESHOLD=0 to never auto-disable.
+func SourceFailureThreshold() int {
+	return envInt("SOURCE_FAILURE_THRESHOLD", 10)
+}
+
+// BackoffDelay returns how long to wait before retrying a source that has failed
+// failures times in a row: its normal interval doubled per failure, capped at maxBackoff
+func BackoffDelay(interval time.Duration, failures int) time.Duration {
+	if failures <= 0 {
+		return interval
+	}
+
+	// Sources polled less than daily are simply retried on their own interval
+	if interval >= maxBackoff {
+		return interval
+	}
+
+	delay := interval
+	for i := 1; i < failures; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
+
+// RecordFetchSuccess clears a source's failure state after a successful fetch
+// and schedules its next poll
+func RecordFetchSuccess(app *models.App, source models.NewsSource, result FeedFetchResult) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"consecutive_failures": 0,
+		"last_success_at":      now,
+		"last_status_code":     result.StatusCode,
+	}
+
+	// A 304 carries no feed, so keep the hint we learned last time
+	if result.Feed != nil {
+		source.UpdateHintMinutes = int(result.UpdateHint / time.Minute)
+		updates["update_hint_minutes"] = source.UpdateHintMinutes
+	}
+
+	if source.AdaptivePolling {
+		if learned := learnPollInterval(app, source); learned > 0 {
+			source.AdaptiveIntervalMinutes = int(learned / time.Minute)
+			updates["adaptive_interval_minutes"] = source.AdaptiveIntervalMinutes
+		}
+	}
+
+	updates["next_fetch_at"] = now.Add(PollInterval(source))
+
+	if err := app.DB.Model(&models.NewsSource{}).Where("id = ?", source.ID).UpdateColumns(updates).Error; err != nil {
+		log.Printf("Error recording fetch success for %s: %v", source.Name, err)
+	}
+}
+
+// RecordFetchFailure stores the error, schedules the next attempt with exponential
+// backoff and deactivates the source once it crosses the failure threshold
+func RecordFetchFailure(app *models.App, source models.NewsSource, statusCode int, fetchErr error) {
+	now := time.Now()
+	failures := source.ConsecutiveFailures + 1
+
+	updates := map[string]interface{}{
+		"consecutive_failures": failures,
+		"last_error_at":        now,
+		"last_error":           fetchErr.Error(),
+		"last_status_code":     statusCode,
+	}
+
+	// Never come back sooner than the server asked us to
+	delay := BackoffDelay(PollInterval(source), failures)
+	var statusErr *HTTPStatusError
+	if errors.As(fetchErr, &statusErr) && statusErr.RetryAfter > delay {
+		delay = statusErr.RetryAfter
+	}
+	updates["next_fetch_at"] = now.Add(delay)
+
+	threshold := SourceFailureThreshold()
+	if threshold > 0 && failures >= threshold {
+		updates["active"] = false
+		updates["auto_disabled_at"] = now
+		log.Printf("⚠️ Deactivating %s after %d consecutive failures", source.Name, failures)
+	} else {
+		log.Printf("%s has failed %d times in a row, next attempt after %s", source.Name, failures, delay)
+	}
+
+	if err := app.DB.Model(&models.NewsSource{}).Where("id = ?", source.ID).UpdateColumns(updates).Error; err != nil {
+		log.Printf("Error recording fetch failure for %s: %v", source.Name, err)
+	}
+}
+
+// DeferSource pushes a source's next poll back without counting a failure,
+// used when its host is rate limiting us
+func DeferSource(app *models.App, source models.NewsSource, until time.Time) {
+	if err := app.DB.Model(&models.NewsSource{}).Where("id = ?", source.ID).UpdateColumn("next_fetch_at", until).Error; err != nil {
+		log.Printf("Error deferring %s: %v", source.Name, err)
+	}
+}
+
+// ResetSourceHealth clears failure and backoff state, e.g. when a source is re-enabled by hand
+func ResetSourceHealth(db *gorm.DB, sourceID uint) error {
+	return db.Model(&models.NewsSource{}).Where("id = ?", sourceID).UpdateColumns(map[string]interface{}{
+		"consecutive_failures": 0,
+		"next_fetch_at":        nil,
+		"auto_disabled_at":     nil,
+	}).Error
+}
+
+// SourceHealth is the fetch health of one source as reported by the API
+type SourceHealth struct {
+	SourceID            uint       `json:"source_id"`
+	Name                string     `json:"name"`
+	Active              bool       `json:"active"`
+	Status              string     `json:"status"` // "healthy", "failing", "disabled" or "auto_disabled"
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	FailureThreshold    int        `json:"failure_threshold"`
+	LastSuccessAt       *time.Time `json:"last_success_at"`
+	LastErrorAt         *time.Time `json:"last_error_at"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastStatusCode      int        `json:"last_status_code,omitempty"`
+	NextFetchAt         *time.Time `json:"next_fetch_at"`
+	AutoDisabledAt      *time.Time `json:"auto_disabled_at"`
+}
+
+// GetSourceHealth summarizes a source's fetch health
+func GetSourceHealth(source models.NewsSource) SourceHealth {
+	status := "healthy"
+	switch {
+	case !source.Active && source.AutoDisabledAt != nil:
+		status = "auto_disabled"
+	case !source.Active:
+		status = "disabled"
+	case source.ConsecutiveFailures > 0:
+		status = "failing"
+	}
+
+	return SourceHealth{
+		SourceID:            source.ID,
+		Name:                source.Name,
+		Active:              source.Active,
+		Status:              status,
+		ConsecutiveFailures: source.ConsecutiveFailures,
+		FailureThreshold:    SourceFailureThreshold(),
+		LastSuccessAt:       source.LastSuccessAt,
+		LastErrorAt:         source.LastErrorAt,
+		LastError:           source.LastError,
+		LastStatusCode:      source.LastStatusCode,
+		NextFetchAt:         source.NextFetchAt,
+		AutoDisabledAt:      source.AutoDisabledAt,
+	}
+}
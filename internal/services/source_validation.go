@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+)
+
+// feedValidationTimeout bounds how long we wait on a candidate feed
+const feedValidationTimeout = 20 * time.Second
+
+// ValidateFeedURL checks that rssURL is an absolute http(s) URL and that it
+// actually parses as a feed, returning the parsed feed on success
+func ValidateFeedURL(app *models.App, rssURL string) (*gofeed.Feed, error) {
+	parsedURL, err := url.Parse(strings.TrimSpace(rssURL))
+	if err != nil {
+		return nil, fmt.Errorf("invalid feed URL: %v", err)
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return nil, fmt.Errorf("feed URL must use http or https")
+	}
+	if parsedURL.Host == "" {
+		return nil, fmt.Errorf("feed URL must include a host")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), feedValidationTimeout)
+	defer cancel()
+
+	feed, err := app.Parser.ParseURLWithContext(parsedURL.String(), ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse feed at %s: %v", parsedURL.String(), err)
+	}
+
+	return feed, nil
+}
@@ -0,0 +1,345 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+	"gorm.io/gorm"
+)
+
+// Alert types
+const (
+	AlertTypeMatch = "match" // Notify on every matching article
+	AlertTypeSpike = "spike" // Notify when coverage of a keyword or source spikes
+)
+
+// Spike alert defaults and limits
+const (
+	defaultSpikeWindowMinutes = 60
+	defaultSpikeBaselineHours = 7 * 24
+	defaultSpikeFactor        = 3.0
+	defaultSpikeMinArticles   = 5
+
+	minSpikeWindowMinutes = 5
+	maxSpikeWindowMinutes = 24 * 60
+	maxSpikeBaselineHours = 30 * 24
+
+	// spikeCooldownWindows is how many windows a series stays quiet after firing,
+	// so a sustained spike isn't reported on every check
+	spikeCooldownWindows = 3
+	// spikeSampleArticles is how many of the window's articles a spike notification lists
+	spikeSampleArticles = 10
+)
+
+// spikeSourcePrefix marks a SpikeEvent series that tracks a source rather than a keyword
+const spikeSourcePrefix = "source:"
+
+// spikeArticleTime is when an article counts as having appeared: when it was
+// published, so a feed's backlog ingested all at once (a new or re-enabled
+// source, catching up after downtime) lands in the past rather than in the
+// current window, but never later than when we stored it, so a bogus future
+// pub_date can't push it forward
+const spikeArticleTime = "LEAST(pub_date, created_at)"
+
+// ValidateAlertType checks an alert's type and, for spike alerts, their
+// settings, filling in defaults for the ones left empty
+func ValidateAlertType(alert *models.UserAlert) error {
+	if alert.Type == "" {
+		alert.Type = AlertTypeMatch
+	}
+	if alert.Type == AlertTypeMatch {
+		return nil
+	}
+	if alert.Type != AlertTypeSpike {
+		return fmt.Errorf("type must be match or spike")
+	}
+
+	if len(alert.Keywords) == 0 && len(alert.SourceIDs) == 0 {
+		return fmt.Errorf("spike alerts need keywords or source_ids to track")
+	}
+	// Keywords are counted against the words extractKeywords stores for each
+	// article, so only words it would store can ever spike
+	for i, keyword := range alert.Keywords {
+		keyword = strings.TrimSpace(keyword)
+		if strings.ContainsFunc(keyword, unicode.IsSpace) {
+			return fmt.Errorf("spike keyword %q must be a single word", keyword)
+		}
+		normalized := indexableKeyword(keyword)
+		if normalized == "" {
+			return fmt.Errorf("spike keyword %q is too short or too common to track; use words of at least 4 letters", keyword)
+		}
+		alert.Keywords[i] = normalized
+	}
+	if strings.TrimSpace(alert.Query) != "" {
+		return fmt.Errorf("spike alerts track keywords and source_ids; query isn't supported")
+	}
+	if IsDigestMode(alert.DeliveryMode) {
+		return fmt.Errorf("spike alerts are always delivered instantly")
+	}
+
+	if alert.SpikeWindowMinutes == 0 {
+		alert.SpikeWindowMinutes = defaultSpikeWindowMinutes
+	}
+	if alert.SpikeWindowMinutes < minSpikeWindowMinutes || alert.SpikeWindowMinutes > maxSpikeWindowMinutes {
+		return fmt.Errorf("spike_window_minutes must be between %d and %d", minSpikeWindowMinutes, maxSpikeWindowMinutes)
+	}
+
+	if alert.SpikeBaselineHours == 0 {
+		alert.SpikeBaselineHours = defaultSpikeBaselineHours
+	}
+	if alert.SpikeBaselineHours*60 < 2*alert.SpikeWindowMinutes || alert.SpikeBaselineHours > maxSpikeBaselineHours {
+		return fmt.Errorf("spike_baseline_hours must cover at least two windows and be at most %d", maxSpikeBaselineHours)
+	}
+
+	if alert.SpikeFactor == 0 {
+		alert.SpikeFactor = defaultSpikeFactor
+	}
+	if alert.SpikeFactor <= 1 {
+		return fmt.Errorf("spike_factor must be greater than 1")
+	}
+
+	if alert.SpikeMinArticles == 0 {
+		alert.SpikeMinArticles = defaultSpikeMinArticles
+	}
+	if alert.SpikeMinArticles < 1 {
+		return fmt.Errorf("spike_min_articles must be at least 1")
+	}
+
+	return nil
+}
+
+// spikeSeries is one keyword or source a spike alert tracks
+type spikeSeries struct {
+	Key   string // SpikeEvent.Series
+	Label string
+}
+
+// spikeSeriesFor lists what alert tracks: each keyword (within its sources,
+// if any), or each source when it has no keywords
+func spikeSeriesFor(app *models.App, alert models.UserAlert) []spikeSeries {
+	var series []spikeSeries
+	if len(alert.Keywords) > 0 {
+		for _, keyword := range removeDuplicates(lowerAll(alert.Keywords)) {
+			if keyword == "" {
+				continue
+			}
+			series = append(series, spikeSeries{Key: keyword, Label: keyword})
+		}
+		return series
+	}
+
+	var sources []models.NewsSource
+	app.DB.Unscoped().Select("id", "name").Where("id IN ?", []int64(alert.SourceIDs)).Find(&sources)
+	names := map[uint]string{}
+	for _, source := range sources {
+		names[source.ID] = source.Name
+	}
+
+	for _, id := range alert.SourceIDs {
+		label := names[uint(id)]
+		if label == "" {
+			label = "Source " + strconv.FormatInt(id, 10)
+		}
+		series = append(series, spikeSeries{Key: spikeSourcePrefix + strconv.FormatInt(id, 10), Label: label})
+	}
+	return series
+}
+
+// spikeScope restricts articles to the ones counted for series of alert
+func spikeScope(db *gorm.DB, alert models.UserAlert, series string) *gorm.DB {
+	if _, ok := strings.CutPrefix(series, spikeSourcePrefix); ok {
+		return spikeSourceScope(db, alert, series)
+	}
+	// Stored keywords are already lowercase, so containment can use the GIN index on keywords
+	return spikeSourceScope(db, alert, series).
+		Where("keywords @> ARRAY[?]::text[]", series)
+}
+
+// spikeSourceScope restricts articles to the sources series of alert is
+// counted over, regardless of keyword
+func spikeSourceScope(db *gorm.DB, alert models.UserAlert, series string) *gorm.DB {
+	scope := db.Model(&models.Article{})
+
+	if id, ok := strings.CutPrefix(series, spikeSourcePrefix); ok {
+		return scope.Where("source_id = ?", id)
+	}
+	if len(alert.SourceIDs) > 0 {
+		scope = scope.Where("source_id IN ?", []int64(alert.SourceIDs))
+	}
+	return scope
+}
+
+// CheckSpikeAlerts compares every active spike alert's current window with
+// its baseline and queues notifications for the series that spiked,
+// returning how many fired
+func CheckSpikeAlerts(app *models.App) (int, error) {
+	now := time.Now()
+
+	var alerts []models.UserAlert
+	err := app.DB.Where("type = ? AND active = ? AND (snoozed_until IS NULL OR snoozed_until <= ?)", AlertTypeSpike, true, now).
+		Find(&alerts).Error
+	if err != nil {
+		return 0, err
+	}
+
+	fired := 0
+	for _, alert := range alerts {
+		for _, series := range spikeSeriesFor(app, alert) {
+			event, err := checkSpike(app, alert, series, now)
+			if err != nil {
+				log.Printf("Error checking spike alert %d (%s): %v", alert.ID, series.Label, err)
+				continue
+			}
+			if event != nil {
+				log.Printf("📈 Spike alert %d fired for %q: %d articles vs %.1f expected", alert.ID, series.Label, event.Count, event.Baseline)
+				fired++
+			}
+		}
+	}
+
+	if fired > 0 {
+		go func() {
+			if _, err := DispatchNotifications(app); err != nil {
+				log.Printf("Error dispatching notifications: %v", err)
+			}
+		}()
+	}
+
+	return fired, nil
+}
+
+// checkSpike evaluates one series and, if it spiked and isn't cooling down,
+// records a SpikeEvent and queues its notifications
+func checkSpike(app *models.App, alert models.UserAlert, series spikeSeries, now time.Time) (*models.SpikeEvent, error) {
+	window := time.Duration(alert.SpikeWindowMinutes) * time.Minute
+	baselinePeriod := time.Duration(alert.SpikeBaselineHours) * time.Hour
+	windowStart := now.Add(-window)
+	baselineStart := windowStart.Add(-baselinePeriod)
+
+	// Until we've been collecting these sources for the whole baseline period,
+	// the baseline is artificially low and anything would look like a spike
+	var firstSeen sql.NullTime
+	err := spikeSourceScope(app.DB, alert, series.Key).Select("MIN(created_at)").Row().Scan(&firstSeen)
+	if err != nil {
+		return nil, err
+	}
+	if !firstSeen.Valid || firstSeen.Time.After(baselineStart) {
+		return nil, nil
+	}
+
+	var current int64
+	err = spikeScope(app.DB, alert, series.Key).
+		Where(spikeArticleTime+" >= ? AND "+spikeArticleTime+" < ?", windowStart, now).
+		Count(&current).Error
+	if err != nil {
+		return nil, err
+	}
+	if int(current) < alert.SpikeMinArticles {
+		return nil, nil
+	}
+
+	var history int64
+	err = spikeScope(app.DB, alert, series.Key).
+		Where(spikeArticleTime+" >= ? AND "+spikeArticleTime+" < ?", baselineStart, windowStart).
+		Count(&history).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Average articles per window over the baseline period
+	baseline := float64(history) * window.Hours() / baselinePeriod.Hours()
+	if float64(current) < alert.SpikeFactor*baseline {
+		return nil, nil
+	}
+
+	var event *models.SpikeEvent
+	err = app.DB.Transaction(func(tx *gorm.DB) error {
+		// Serialise with other instances checking the same alert
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", LockNamespaceAlert, int32(alert.ID)).Error; err != nil {
+			return err
+		}
+
+		var recent int64
+		err := tx.Model(&models.SpikeEvent{}).
+			Where("alert_id = ? AND series = ? AND created_at > ?", alert.ID, series.Key, now.Add(-spikeCooldownWindows*window)).
+			Count(&recent).Error
+		if err != nil || recent > 0 {
+			return err
+		}
+
+		e := models.SpikeEvent{
+			AlertID:     alert.ID,
+			UserID:      alert.UserID,
+			Series:      series.Key,
+			Label:       series.Label,
+			WindowStart: windowStart,
+			WindowEnd:   now,
+			Count:       int(current),
+			Baseline:    baseline,
+		}
+		if baseline > 0 {
+			e.Ratio = float64(current) / baseline
+		}
+		if err := tx.Create(&e).Error; err != nil {
+			return err
+		}
+
+		var notifications []models.NotificationSent
+		for _, method := range alertMethods(alert) {
+			notifications = append(notifications, models.NotificationSent{Method: method})
+		}
+
+		var webhooks []models.AlertWebhook
+		if err := tx.Where("alert_id = ? AND active = ?", alert.ID, true).Find(&webhooks).Error; err != nil {
+			return err
+		}
+		for _, webhook := range webhooks {
+			webhookID := webhook.ID
+			notifications = append(notifications, models.NotificationSent{Method: MethodAlertWebhook, WebhookID: &webhookID})
+		}
+
+		for _, notification := range notifications {
+			spikeID := e.ID
+			notification.UserID = alert.UserID
+			notification.AlertID = alert.ID
+			notification.SpikeID = &spikeID
+
+			if err := queueNotification(tx, &notification, now); err != nil {
+				return err
+			}
+		}
+
+		event = &e
+		return nil
+	})
+	return event, err
+}
+
+// loadSpikeArticles returns the newest articles counted in a spike's window
+func loadSpikeArticles(app *models.App, alert models.UserAlert, event models.SpikeEvent) ([]models.Article, error) {
+	var articles []models.Article
+	err := spikeScope(app.DB, alert, event.Series).
+		Preload("Source", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).
+		Where(spikeArticleTime+" >= ? AND "+spikeArticleTime+" < ?", event.WindowStart, event.WindowEnd).
+		Order(spikeArticleTime + " DESC").
+		Limit(spikeSampleArticles).
+		Find(&articles).Error
+	return articles, err
+}
+
+// lowerAll lowercases every string in values
+func lowerAll(values []string) []string {
+	lowered := make([]string, len(values))
+	for i, value := range values {
+		lowered[i] = strings.ToLower(strings.TrimSpace(value))
+	}
+	return lowered
+}
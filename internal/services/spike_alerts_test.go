@@ -0,0 +1,42 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/mrrobotisreal/rss_today_api/internal/models"
+)
+
+func TestValidateAlertTypeSpikeKeywords(t *testing.T) {
+	tests := []struct {
+		keyword string
+		want    string // Normalized keyword; "" if it's rejected
+	}{
+		{"Ukraine", "ukraine"},
+		{"  Tesla ", "tesla"},
+		{"(Brexit)", "brexit"},
+		{"war", ""},
+		{"EU", ""},
+		{"climate change", ""},
+		{"with", ""}, // Stop word
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.keyword, func(t *testing.T) {
+			alert := models.UserAlert{Type: AlertTypeSpike, Keywords: pq.StringArray{tt.keyword}}
+			err := ValidateAlertType(&alert)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("ValidateAlertType accepted %q", tt.keyword)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateAlertType returned error: %v", err)
+			}
+			if alert.Keywords[0] != tt.want {
+				t.Errorf("keyword = %q, want %q", alert.Keywords[0], tt.want)
+			}
+		})
+	}
+}